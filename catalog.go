@@ -0,0 +1,110 @@
+package migrator
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	regExprCast    = regexp.MustCompile(`::[a-z_][\w]*( varying| precision| with time zone| without time zone)?(\[\])?`)
+	regExprCollate = regexp.MustCompile(`\s+collate\s+\S+`)
+	// MySQL prints string literals with their character set introducer, like _utf8mb4'draft'
+	regExprIntroducer = regexp.MustCompile(`(^|[^\w'])_[a-z0-9]+'`)
+	regExprIgnored    = regexp.MustCompile("[\\s()\"`\\[\\]]+")
+)
+
+// Index is an index read from the database catalog
+type Index struct {
+	Name    string   `json:"name"`
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique,omitempty"`
+	// Primary is set for the index backing the primary key
+	Primary bool `json:"primary,omitempty"`
+	// Constraint is set for indexes created by a UNIQUE or PRIMARY KEY constraint rather than CREATE INDEX
+	Constraint bool `json:"constraint,omitempty"`
+	// Definition is the statement that recreates the index
+	Definition string `json:"definition"`
+	// Keys lists every key part in order, a column or an expression followed by DESC when descending.
+	// It is nil when the catalog cannot describe every key part.
+	Keys []string `json:"keys,omitempty"`
+	// Class is FULLTEXT or SPATIAL for those kinds of MySQL index
+	Class string `json:"class,omitempty"`
+	// Method is the access method, like btree, hash or gin, and empty where the database has only one
+	Method string `json:"method,omitempty"`
+	// Where is the predicate of a partial index
+	Where string `json:"where,omitempty"`
+}
+
+// ForeignKey is a foreign key constraint read from the database catalog
+type ForeignKey struct {
+	Name              string   `json:"name"`
+	Table             string   `json:"table"`
+	Columns           []string `json:"columns"`
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+	OnDelete          string   `json:"on_delete,omitempty"`
+	OnUpdate          string   `json:"on_update,omitempty"`
+}
+
+// Check is a check constraint read from the database catalog
+type Check struct {
+	Name       string `json:"name"`
+	Table      string `json:"table"`
+	Expression string `json:"expression"`
+	// Columns lists the columns the check references, when the catalog records them
+	Columns []string `json:"columns,omitempty"`
+}
+
+// References reports whether the index covers column
+func (idx Index) References(column string) bool {
+	return containsString(idx.Columns, column)
+}
+
+// References reports whether the foreign key is declared on column
+func (fk ForeignKey) References(column string) bool {
+	return containsString(fk.Columns, column)
+}
+
+// References reports whether the check expression uses column
+func (chk Check) References(column string) bool {
+	if len(chk.Columns) > 0 {
+		return containsString(chk.Columns, column)
+	}
+	return regexp.MustCompile("(^|[^\\w])[\"`\\[]?" + regexp.QuoteMeta(column) + "[\"`\\]]?([^\\w]|$)").MatchString(chk.Expression)
+}
+
+// normalizeExpression reduces an index key part, predicate or check expression to a form that compares
+// equal however the catalog printed it: catalogs freely add parentheses, quotes, casts and character sets
+func normalizeExpression(expr string) string {
+	expr = strings.ToLower(expr)
+	expr = regExprCast.ReplaceAllString(expr, "")
+	expr = regExprIntroducer.ReplaceAllString(expr, "$1'")
+	expr = regExprCollate.ReplaceAllString(expr, "")
+	expr = strings.TrimSuffix(strings.TrimSpace(expr), " asc")
+	return regExprIgnored.ReplaceAllString(expr, "")
+}
+
+// lookupIndex returns the index called name
+func lookupIndex(indexes []Index, name string) (Index, bool) {
+	for _, idx := range indexes {
+		if idx.Name == name {
+			return idx, true
+		}
+	}
+	return Index{}, false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteString renders value as a SQL string literal
+func quoteString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
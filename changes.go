@@ -0,0 +1,154 @@
+package migrator
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Severity classifies how a generated change affects existing data
+type Severity int
+
+const (
+	// Safe changes only add to the schema
+	Safe Severity = iota
+	// Blocking changes keep data but may lock or rewrite the table, or fail on existing rows
+	Blocking
+	// Destructive changes lose data: dropped tables and columns, and narrowed column types
+	Destructive
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Safe:
+		return "safe"
+	case Blocking:
+		return "blocking"
+	case Destructive:
+		return "destructive"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// Change describes one change AutoMigrate generated
+type Change struct {
+	Severity Severity
+	// Action is what the change does, like "drop column"
+	Action string
+	Table  string
+	// Column is set for changes to a single column
+	Column string
+	// Detail explains the change, like the old and new column type
+	Detail string
+}
+
+func (c Change) String() string {
+	s := c.Action + " " + c.Table
+	if c.Column != "" {
+		s += "." + c.Column
+	}
+	if c.Detail != "" {
+		s += " (" + c.Detail + ")"
+	}
+	return s
+}
+
+// destructiveChanges filters the destructive changes out of changes
+func destructiveChanges(changes []Change) []Change {
+	var destructive []Change
+	for _, c := range changes {
+		if c.Severity == Destructive {
+			destructive = append(destructive, c)
+		}
+	}
+	return destructive
+}
+
+// checkDestructive returns a *DestructiveChangeError for destructive changes, unless they are
+// allowed or the user confirms them
+func (m *Migrator) checkDestructive(changes []Change) error {
+	destructive := destructiveChanges(changes)
+	if len(destructive) == 0 || m.AllowDestructive {
+		return nil
+	}
+
+	err := &DestructiveChangeError{Changes: destructive}
+	if m.Confirm != nil && m.Confirm(err.Error()+"\nWrite the migration anyway?") {
+		return nil
+	}
+	return err
+}
+
+// addColumnSeverity classifies adding field, a NOT NULL column without default fails on a table with rows
+func addColumnSeverity(field *schema.Field) Severity {
+	if field.NotNull && !field.HasDefaultValue && !field.PrimaryKey {
+		return Blocking
+	}
+	return Safe
+}
+
+// alterColumnSeverity classifies changing the attributes of column into field: narrowing its size or
+// precision, or converting it to another kind of value, can lose data, while rewriting its type or adding
+// NOT NULL or UNIQUE checks every row
+func alterColumnSeverity(field *schema.Field, column gorm.ColumnType, changes ColumnChanges) (Severity, string) {
+	if !changes.Type {
+		if (changes.Nullable && field.NotNull) || (changes.Unique && field.Unique) {
+			return Blocking, ""
+		}
+		return Safe, ""
+	}
+	if length, ok := column.Length(); ok && length > 0 && field.Size > 0 && int64(field.Size) < length {
+		return Destructive, fmt.Sprintf("size %d to %d", length, field.Size)
+	}
+	if precision, scale, ok := column.DecimalSize(); ok && field.Precision > 0 {
+		if int64(field.Precision) < precision || int64(field.Scale) < scale {
+			return Destructive, fmt.Sprintf("precision (%d,%d) to (%d,%d)", precision, scale, field.Precision, field.Scale)
+		}
+	}
+	if from, to := valueKind(column.ScanType()), fieldKind(field); from != "" && to != "" && from != to {
+		return Destructive, fmt.Sprintf("%s to %s", column.DatabaseTypeName(), field.DataType)
+	}
+	return Blocking, ""
+}
+
+// valueKind groups a scan type into the kind of value it holds
+func valueKind(t reflect.Type) string {
+	switch t {
+	case reflect.TypeOf(false), reflect.TypeOf(sql.NullBool{}):
+		return "bool"
+	case reflect.TypeOf(int64(0)), reflect.TypeOf(sql.NullInt64{}):
+		return "int"
+	case reflect.TypeOf(float64(0)), reflect.TypeOf(sql.NullFloat64{}):
+		return "float"
+	case reflect.TypeOf(time.Time{}), reflect.TypeOf(sql.NullTime{}):
+		return "time"
+	case reflect.TypeOf([]byte(nil)):
+		return "bytes"
+	case reflect.TypeOf(""), reflect.TypeOf(sql.NullString{}):
+		return "string"
+	}
+	return ""
+}
+
+// fieldKind groups a field's data type into the kind of value it holds
+func fieldKind(field *schema.Field) string {
+	switch field.DataType {
+	case schema.Bool:
+		return "bool"
+	case schema.Int, schema.Uint:
+		return "int"
+	case schema.Float:
+		return "float"
+	case schema.Time:
+		return "time"
+	case schema.Bytes:
+		return "bytes"
+	case schema.String:
+		return "string"
+	}
+	return ""
+}
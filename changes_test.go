@@ -0,0 +1,99 @@
+package migrator
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// migrationFiles returns the names of the files in the migration folder of m
+func migrationFiles(t *testing.T, m *Migrator) []string {
+	t.Helper()
+	entries, err := os.ReadDir(m.migrationPath)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestCreateDestructiveChange(t *testing.T) {
+	tests := []struct {
+		name    string
+		confirm func(question string) bool
+		written bool
+	}{
+		{name: "refused without Confirm"},
+		{name: "declined", confirm: func(string) bool { return false }},
+		{name: "confirmed", confirm: func(string) bool { return true }, written: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testMigrator(t, openSQLite(t))
+			migrateTo(t, m, &planAuthorWithBio{})
+			existing := migrationFiles(t, m)
+
+			var questions []string
+			m.AllowDestructive = false
+			if tt.confirm != nil {
+				m.Confirm = func(question string) bool {
+					questions = append(questions, question)
+					return tt.confirm(question)
+				}
+			}
+			m.Models = []interface{}{&planAuthor{}}
+			err := m.Run(m.DB, "create", "drop_bio")
+
+			if tt.confirm != nil && (len(questions) != 1 || !strings.Contains(questions[0], "drop column plan_authors.bio")) {
+				t.Errorf("Confirm asked %q, want about drop column plan_authors.bio", questions)
+			}
+			files := migrationFiles(t, m)
+			if tt.written {
+				if err != nil {
+					t.Fatalf("create: %v", err)
+				}
+				if len(files) == len(existing) {
+					t.Error("no migration written")
+				}
+				return
+			}
+
+			var destructive *DestructiveChangeError
+			if !errors.As(err, &destructive) || !errors.Is(err, ErrDestructiveChange) {
+				t.Fatalf("create = %v, want a *DestructiveChangeError", err)
+			}
+			if len(destructive.Changes) != 1 || destructive.Changes[0].String() != "drop column plan_authors.bio" {
+				t.Errorf("Changes = %v, want drop column plan_authors.bio", destructive.Changes)
+			}
+			if !strings.Contains(err.Error(), "plan_authors.bio") {
+				t.Errorf("error %q does not name plan_authors.bio", err)
+			}
+			if strings.Join(files, ",") != strings.Join(existing, ",") {
+				t.Errorf("files = %v, want %v", files, existing)
+			}
+		})
+	}
+}
+
+func TestDryRunWarnsOfDestructiveChanges(t *testing.T) {
+	m := testMigrator(t, openSQLite(t))
+	migrateTo(t, m, &planAuthorWithBio{})
+	existing := migrationFiles(t, m)
+
+	var out bytes.Buffer
+	m.AllowDestructive, m.DryRun, m.Output = false, true, &out
+	m.Models = []interface{}{&planAuthor{}}
+	mustRun(t, m, "create", "drop_bio")
+
+	if !strings.Contains(out.String(), "-- WARNING: destructive change: drop column plan_authors.bio\n") {
+		t.Errorf("output does not warn of dropping plan_authors.bio:\n%s", out.String())
+	}
+	if files := migrationFiles(t, m); strings.Join(files, ",") != strings.Join(existing, ",") {
+		t.Errorf("files = %v, want %v", files, existing)
+	}
+}
@@ -0,0 +1,246 @@
+// Package cli implements the migrator command line interface.
+//
+// Models are compiled Go types, so commands that diff them against the
+// database (create, diff and check) need a binary built inside the module that
+// declares them. Run `migrator init` to scaffold one, or call Execute from
+// your own main package with a setup function that registers the models.
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alob-mtc/migrator"
+	"github.com/mattn/go-isatty"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// SetupFunc configures every Migrator the CLI creates, usually by registering models
+type SetupFunc func(*migrator.Migrator)
+
+// errNoModels is returned by commands that need registered models when there are none
+var errNoModels = errors.New("no models registered, run `migrator init` to scaffold a main package that registers them")
+
+type options struct {
+	dsn           string
+	shadowDSN     string
+	dialect       string
+	path          string
+	verbose       bool
+	ignoreTables  []string
+	managedOnly   bool
+	offline       bool
+	sqliteVersion string
+}
+
+// Execute runs the migrator command and exits with a non-zero status on failure
+func Execute(setup SetupFunc) {
+	if err := Command(setup).Execute(); err != nil {
+		logrus.Error(err)
+		os.Exit(1)
+	}
+}
+
+// Command returns the root migrator command, setup may be nil
+func Command(setup SetupFunc) *cobra.Command {
+	opts := &options{}
+
+	root := &cobra.Command{
+		Use:           "migrator",
+		Short:         "Generate and run SQL migrations from GORM models",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&opts.dsn, "dsn", os.Getenv("MIGRATOR_DSN"), "database connection string (env MIGRATOR_DSN)")
+	flags.StringVar(&opts.shadowDSN, "shadow-dsn", os.Getenv("MIGRATOR_SHADOW_DSN"), "scratch database create, diff and check apply the migrations to and compare the models with, everything in it is dropped (env MIGRATOR_SHADOW_DSN)")
+	flags.StringVar(&opts.dialect, "dialect", envOr("MIGRATOR_DIALECT", "postgres"), "database dialect: postgres, mysql, sqlite or sqlserver (env MIGRATOR_DIALECT)")
+	flags.StringVar(&opts.path, "path", envOr("MIGRATOR_PATH", "migrations/sql/"), "migrations folder (env MIGRATOR_PATH)")
+	flags.BoolVarP(&opts.verbose, "verbose", "v", false, "log SQL statements")
+	flags.StringSliceVar(&opts.ignoreTables, "ignore-table", nil, "table name or glob never to drop, repeatable")
+	flags.BoolVar(&opts.managedOnly, "managed-only", false, "only drop tables created or renamed by a migration in --path")
+	flags.BoolVar(&opts.offline, "offline", false, "compare the models with the snapshot of the latest migration in --path instead of a database, --dsn is optional")
+	flags.StringVar(&opts.sqliteVersion, "sqlite-version", "", "SQLite release --offline migrations are generated for, defaults to one that drops columns in place")
+
+	run := func(command string, needsModels bool) func(*cobra.Command, []string) error {
+		return func(cmd *cobra.Command, args []string) error {
+			db, mg, err := opts.open(setup, cmd)
+			if err != nil {
+				return err
+			}
+			if needsModels && len(mg.Models) == 0 {
+				return errNoModels
+			}
+			return mg.Run(db, command, args...)
+		}
+	}
+
+	root.AddCommand(
+		&cobra.Command{
+			Use:   "up [N]",
+			Short: "Apply all pending migrations, or the next N",
+			Args:  cobra.MaximumNArgs(1),
+			RunE:  run("up", false),
+		},
+		&cobra.Command{
+			Use:   "down [N]",
+			Short: "Roll back the last migration, or the last N",
+			Args:  cobra.MaximumNArgs(1),
+			RunE:  run("down", false),
+		},
+		&cobra.Command{
+			Use:   "status",
+			Short: "Print the current migration version and dirty flag",
+			Args:  cobra.NoArgs,
+			RunE:  run("version", false),
+		},
+		&cobra.Command{
+			Use:     "force <version>",
+			Short:   "Set the migration version without running migrations, clearing the dirty flag, -1 for none",
+			Example: "  migrator force 1657112223\n  migrator force -- -1",
+			Args:    cobra.ExactArgs(1),
+			RunE:    run("force", false),
+		},
+		createCommand(opts, setup),
+		&cobra.Command{
+			Use:   "diff",
+			Short: "Print the SQL a new migration would contain without writing any files",
+			Args:  cobra.NoArgs,
+			RunE:  run("diff", true),
+		},
+		&cobra.Command{
+			Use:   "check",
+			Short: "Fail when the database differs from the models, listing the changes a migration would make",
+			Args:  cobra.NoArgs,
+			RunE:  run("check", true),
+		},
+		initCommand(),
+	)
+
+	return root
+}
+
+func createCommand(opts *options, setup SetupFunc) *cobra.Command {
+	var dryRun, allowDestructive bool
+
+	cmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Generate a migration from the difference between the models and the database",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, mg, err := opts.open(setup, cmd)
+			if err != nil {
+				return err
+			}
+			if len(mg.Models) == 0 {
+				return errNoModels
+			}
+			mg.DryRun = dryRun
+			mg.AllowDestructive = allowDestructive
+			return mg.Run(db, "create", args...)
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the migration instead of writing it")
+	cmd.Flags().BoolVar(&allowDestructive, "allow-destructive", false, "write migrations that drop tables or columns or narrow column types without asking")
+
+	return cmd
+}
+
+// open connects to the database and returns a Migrator configured by setup that prints to cmd
+func (opts *options) open(setup SetupFunc, cmd *cobra.Command) (*gorm.DB, *migrator.Migrator, error) {
+	if opts.dsn == "" && !opts.offline {
+		return nil, nil, errors.New("missing --dsn")
+	}
+	if opts.shadowDSN != "" && opts.shadowDSN == opts.dsn {
+		return nil, nil, errors.New("--shadow-dsn must not be the --dsn database, everything in it is dropped")
+	}
+
+	db, err := opts.connect(opts.dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mg := migrator.New(db, opts.path)
+	mg.Output = cmd.OutOrStdout()
+	mg.IgnoreTables = opts.ignoreTables
+	mg.ManagedTablesOnly = opts.managedOnly
+	mg.Offline = opts.offline
+	mg.SQLiteVersion = opts.sqliteVersion
+	if opts.shadowDSN != "" {
+		if mg.ShadowDB, err = opts.connect(opts.shadowDSN); err != nil {
+			return nil, nil, err
+		}
+	}
+	if isatty.IsTerminal(os.Stdin.Fd()) {
+		mg.Confirm = confirm(cmd.InOrStdin(), cmd.ErrOrStderr())
+	}
+	if setup != nil {
+		setup(mg)
+	}
+	return db, mg, nil
+}
+
+// connect opens the database at dsn with the --dialect driver. With --offline nothing connects to it, only
+// SQLite opens an in-memory database in its place.
+func (opts *options) connect(dsn string) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch opts.dialect {
+	case "postgres":
+		dialector = postgres.Open(dsn)
+	case "mysql":
+		dialector = mysql.New(mysql.Config{DSN: dsn, SkipInitializeWithVersion: opts.offline})
+	case "sqlite":
+		if dsn == "" || opts.offline {
+			dsn = ":memory:"
+		}
+		dialector = sqlite.Open(dsn)
+	case "sqlserver":
+		dialector = sqlserver.Open(dsn)
+	default:
+		return nil, fmt.Errorf("%w: %s", migrator.ErrUnsupportedDialect, opts.dialect)
+	}
+
+	logLevel := logger.Silent
+	if opts.verbose {
+		logLevel = logger.Info
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Default.LogMode(logLevel), DisableAutomaticPing: opts.offline})
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %w", opts.dialect, err)
+	}
+	return db, nil
+}
+
+// confirm returns a Config.Confirm that asks the question on w and reads a yes or no answer from r
+func confirm(r io.Reader, w io.Writer) func(string) bool {
+	in := bufio.NewReader(r)
+	return func(question string) bool {
+		fmt.Fprintf(w, "%s [y/N] ", question)
+		answer, _ := in.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return true
+		}
+		return false
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
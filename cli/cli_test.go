@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alob-mtc/migrator"
+)
+
+type cliUser struct {
+	ID   uint
+	Name string
+}
+
+// registerUser is the SetupFunc of the tests, it registers cliUser
+func registerUser(m *migrator.Migrator) {
+	m.RegisterModel(&cliUser{})
+}
+
+// upMigrations returns the up migrations in dir
+func upMigrations(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.up.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	envPath, flagPath := filepath.Join(dir, "env"), filepath.Join(dir, "flag")
+	t.Setenv("MIGRATOR_DSN", filepath.Join(dir, "test.db"))
+	t.Setenv("MIGRATOR_DIALECT", "sqlite")
+	t.Setenv("MIGRATOR_PATH", envPath)
+
+	if out, err := execute(t, registerUser, "create", "create_users"); err != nil {
+		t.Fatalf("create: %v\n%s", err, out)
+	}
+	if files := upMigrations(t, envPath); len(files) != 1 {
+		t.Errorf("migrations in MIGRATOR_PATH = %v, want one", files)
+	}
+
+	// flags take precedence over the environment
+	if out, err := execute(t, registerUser, "create", "create_users", "--path", flagPath); err != nil {
+		t.Fatalf("create --path: %v\n%s", err, out)
+	}
+	if files := upMigrations(t, flagPath); len(files) != 1 {
+		t.Errorf("migrations in --path = %v, want one", files)
+	}
+}
+
+func TestInvalidConnection(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	for _, name := range []string{"MIGRATOR_DSN", "MIGRATOR_SHADOW_DSN", "MIGRATOR_DIALECT", "MIGRATOR_PATH"} {
+		t.Setenv(name, "")
+	}
+
+	if _, err := execute(t, registerUser, "diff", "--dialect", "sqlite"); err == nil || !strings.Contains(err.Error(), "missing --dsn") {
+		t.Errorf("diff without --dsn = %v, want missing --dsn", err)
+	}
+
+	t.Setenv("MIGRATOR_SHADOW_DSN", dsn)
+	if _, err := execute(t, registerUser, "diff", "--dialect", "sqlite", "--dsn", dsn); err == nil || !strings.Contains(err.Error(), "--shadow-dsn must not be the --dsn database") {
+		t.Errorf("diff with the shadow database as --dsn = %v, want it refused", err)
+	}
+	if _, err := os.Stat(dsn); !os.IsNotExist(err) {
+		t.Errorf("%s opened", dsn)
+	}
+	t.Setenv("MIGRATOR_SHADOW_DSN", "")
+
+	if _, err := execute(t, registerUser, "diff", "--dialect", "oracle", "--dsn", dsn); !errors.Is(err, migrator.ErrUnsupportedDialect) {
+		t.Errorf("diff --dialect oracle = %v, want ErrUnsupportedDialect", err)
+	}
+}
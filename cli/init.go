@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/modfile"
+)
+
+var mainTemplate = template.Must(template.New("main").Parse(`// Code generated by migrator init. Register new models below.
+package main
+
+import (
+	"github.com/alob-mtc/migrator"
+	"github.com/alob-mtc/migrator/cli"
+
+	models "{{.ImportPath}}"
+)
+
+func main() {
+	cli.Execute(func(m *migrator.Migrator) {
+		m.RegisterModel(
+		{{- range .Types}}
+			&models.{{.}}{},
+		{{- end}}
+		)
+	})
+}
+`))
+
+// modelsPackage describes the package scaffolded main.go imports models from
+type modelsPackage struct {
+	Name       string
+	ImportPath string
+	Types      []string
+}
+
+func initCommand() *cobra.Command {
+	var (
+		out   string
+		types []string
+		force bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "init <models-dir>",
+		Short: "Scaffold a main package that registers the models declared in models-dir",
+		Long: `Scaffold a main package that registers the models declared in models-dir.
+
+The generated program runs this CLI with the models compiled in, so it can
+generate migrations with create and diff. Every exported struct type in
+models-dir is registered unless --type is given. Build it with
+"go run ./cmd/migrator" from the root of your module.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pkg, err := loadModelsPackage(args[0])
+			if err != nil {
+				return err
+			}
+
+			if len(types) > 0 {
+				declared := map[string]bool{}
+				for _, t := range pkg.Types {
+					declared[t] = true
+				}
+				for _, t := range types {
+					if !declared[t] {
+						return fmt.Errorf("type %s is not declared in %s", t, pkg.ImportPath)
+					}
+				}
+				pkg.Types = types
+			}
+			if len(pkg.Types) == 0 {
+				return fmt.Errorf("no exported struct types found in %s", pkg.ImportPath)
+			}
+
+			if _, err := os.Stat(out); err == nil && !force {
+				return fmt.Errorf("%s already exists, use --force to overwrite it", out)
+			}
+
+			var buf bytes.Buffer
+			if err := mainTemplate.Execute(&buf, pkg); err != nil {
+				return err
+			}
+			src, err := format.Source(buf.Bytes())
+			if err != nil {
+				return err
+			}
+
+			if err := os.MkdirAll(filepath.Dir(out), os.ModePerm); err != nil {
+				return err
+			}
+			if err := os.WriteFile(out, src, 0o644); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "wrote %s, registering %d models from %s\n", out, len(pkg.Types), pkg.ImportPath)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&out, "out", "o", filepath.Join("cmd", "migrator", "main.go"), "file to write")
+	cmd.Flags().StringSliceVarP(&types, "type", "t", nil, "model types to register, defaults to every exported struct type")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "overwrite an existing file")
+
+	return cmd
+}
+
+// loadModelsPackage resolves the import path of dir from the enclosing go.mod
+// and collects the exported struct types it declares
+func loadModelsPackage(dir string) (*modelsPackage, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	modRoot, modPath, err := findModule(dir)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(modRoot, dir)
+	if err != nil {
+		return nil, err
+	}
+	pkg := &modelsPackage{ImportPath: modPath}
+	if rel != "." {
+		pkg.ImportPath += "/" + filepath.ToSlash(rel)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		pkg.Name = f.Name.Name
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if _, ok := ts.Type.(*ast.StructType); ok && ts.Name.IsExported() && ts.TypeParams == nil {
+					pkg.Types = append(pkg.Types, ts.Name.Name)
+				}
+			}
+		}
+	}
+
+	if pkg.Name == "" {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+	if pkg.Name == "main" {
+		return nil, fmt.Errorf("%s is a main package and cannot be imported", pkg.ImportPath)
+	}
+	sort.Strings(pkg.Types)
+	return pkg, nil
+}
+
+// findModule walks up from dir to the nearest go.mod and returns its directory and module path
+func findModule(dir string) (string, string, error) {
+	for d := dir; ; d = filepath.Dir(d) {
+		data, err := os.ReadFile(filepath.Join(d, "go.mod"))
+		if err == nil {
+			if modPath := modfile.ModulePath(data); modPath != "" {
+				return d, modPath, nil
+			}
+			return "", "", fmt.Errorf("no module path in %s", filepath.Join(d, "go.mod"))
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", "", err
+		}
+		if filepath.Dir(d) == d {
+			return "", "", fmt.Errorf("no go.mod found above %s", dir)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files, by their path relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// execute runs the migrator command with args and returns what it printed
+func execute(t *testing.T, setup SetupFunc, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	cmd := Command(setup)
+	cmd.SetArgs(args)
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+// scaffoldedModels parses the main.go init wrote and returns the import path of its models package and
+// the models it registers
+func scaffoldedModels(t *testing.T, file string) (string, []string) {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+	if err != nil {
+		t.Fatalf("scaffolded %s does not parse: %v", file, err)
+	}
+
+	var importPath string
+	imported := map[string]bool{}
+	for _, spec := range f.Imports {
+		pkgPath, _ := strconv.Unquote(spec.Path.Value)
+		name := path.Base(pkgPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if imported[name] {
+			t.Errorf("scaffolded %s imports two packages as %s", pkgPath, name)
+		}
+		imported[name] = true
+		if name == "models" {
+			importPath = pkgPath
+		}
+	}
+	var registered []string
+	ast.Inspect(f, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.CompositeLit); ok {
+			if sel, ok := lit.Type.(*ast.SelectorExpr); ok && sel.X.(*ast.Ident).Name == "models" {
+				registered = append(registered, sel.Sel.Name)
+			}
+		}
+		return true
+	})
+	return importPath, registered
+}
+
+func TestInit(t *testing.T) {
+	// the models package shares its name with the migrator package main.go imports
+	models := `package migrator
+
+type User struct {
+	ID   uint
+	Name string
+}
+
+type Product struct {
+	ID uint
+}
+
+type draft struct{}
+
+type Status string
+`
+	tests := []struct {
+		name       string
+		args       []string
+		registered []string
+		err        string
+	}{
+		{name: "every exported struct", registered: []string{"Product", "User"}},
+		{name: "filtered by type", args: []string{"--type", "User"}, registered: []string{"User"}},
+		{name: "undeclared type", args: []string{"--type", "Order"}, err: "type Order is not declared in example.com/app/models"},
+		{name: "unexported type", args: []string{"--type", "draft"}, err: "type draft is not declared"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{
+				"go.mod":                "module example.com/app\n\ngo 1.18\n",
+				"models/models.go":      models,
+				"models/models_test.go": "package migrator\n\ntype Fixture struct{}\n",
+			})
+			out := filepath.Join(dir, "cmd", "migrator", "main.go")
+
+			printed, err := execute(t, nil, append([]string{"init", filepath.Join(dir, "models"), "--out", out}, tt.args...)...)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("init = %v, want %s", err, tt.err)
+				}
+				if _, err := os.Stat(out); !os.IsNotExist(err) {
+					t.Errorf("%s written", out)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("init: %v\n%s", err, printed)
+			}
+
+			importPath, registered := scaffoldedModels(t, out)
+			if importPath != "example.com/app/models" {
+				t.Errorf("models imported from %q, want example.com/app/models", importPath)
+			}
+			if strings.Join(registered, ",") != strings.Join(tt.registered, ",") {
+				t.Errorf("registered %v, want %v", registered, tt.registered)
+			}
+		})
+	}
+}
+
+func TestInitKeepsExistingFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":           "module example.com/app\n",
+		"models/models.go": "package models\n\ntype User struct{}\n",
+		"main.go":          "package main\n",
+	})
+	out := filepath.Join(dir, "main.go")
+
+	if _, err := execute(t, nil, "init", filepath.Join(dir, "models"), "--out", out); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("init = %v, want it to refuse overwriting %s", err, out)
+	}
+	if _, err := execute(t, nil, "init", filepath.Join(dir, "models"), "--out", out, "--force"); err != nil {
+		t.Fatalf("init --force: %v", err)
+	}
+	if _, registered := scaffoldedModels(t, out); strings.Join(registered, ",") != "User" {
+		t.Errorf("registered %v, want User", registered)
+	}
+}
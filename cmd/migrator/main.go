@@ -0,0 +1,12 @@
+// Command migrator generates and runs SQL migrations for GORM models.
+//
+// This binary has no models compiled in, so it can apply, roll back and
+// repair migrations but not generate them. Run `migrator init` inside your
+// module to scaffold a main package that registers your models.
+package main
+
+import "github.com/alob-mtc/migrator/cli"
+
+func main() {
+	cli.Execute(nil)
+}
@@ -3,8 +3,15 @@ package migrator
 import (
 	"database/sql"
 	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/schema"
 )
 
+var regAutoIncrement = regexp.MustCompile(`(?i)\s+(auto_increment\b|identity\s*\(\s*\d+\s*,\s*\d+\s*\))`)
+
 // ColumnType column type implements ColumnType interface
 type ColumnType struct {
 	SQLColumnType      *sql.ColumnType
@@ -28,6 +35,9 @@ func (ct ColumnType) Name() string {
 	if ct.NameValue.Valid {
 		return ct.NameValue.String
 	}
+	if ct.SQLColumnType == nil {
+		return ""
+	}
 	return ct.SQLColumnType.Name()
 }
 
@@ -41,6 +51,9 @@ func (ct ColumnType) DatabaseTypeName() string {
 	if ct.DataTypeValue.Valid {
 		return ct.DataTypeValue.String
 	}
+	if ct.SQLColumnType == nil {
+		return ""
+	}
 	return ct.SQLColumnType.DatabaseTypeName()
 }
 
@@ -64,6 +77,9 @@ func (ct ColumnType) Length() (length int64, ok bool) {
 	if ct.LengthValue.Valid {
 		return ct.LengthValue.Int64, true
 	}
+	if ct.SQLColumnType == nil {
+		return 0, false
+	}
 	return ct.SQLColumnType.Length()
 }
 
@@ -72,6 +88,9 @@ func (ct ColumnType) DecimalSize() (precision int64, scale int64, ok bool) {
 	if ct.DecimalSizeValue.Valid {
 		return ct.DecimalSizeValue.Int64, ct.ScaleValue.Int64, true
 	}
+	if ct.SQLColumnType == nil {
+		return 0, 0, false
+	}
 	return ct.SQLColumnType.DecimalSize()
 }
 
@@ -80,6 +99,9 @@ func (ct ColumnType) Nullable() (nullable bool, ok bool) {
 	if ct.NullableValue.Valid {
 		return ct.NullableValue.Bool, true
 	}
+	if ct.SQLColumnType == nil {
+		return false, false
+	}
 	return ct.SQLColumnType.Nullable()
 }
 
@@ -93,6 +115,9 @@ func (ct ColumnType) ScanType() reflect.Type {
 	if ct.ScanTypeValue != nil {
 		return ct.ScanTypeValue
 	}
+	if ct.SQLColumnType == nil {
+		return nil
+	}
 	return ct.SQLColumnType.ScanType()
 }
 
@@ -105,3 +130,120 @@ func (ct ColumnType) Comment() (value string, ok bool) {
 func (ct ColumnType) DefaultValue() (value string, ok bool) {
 	return ct.DefaultValueValue.String, ct.DefaultValueValue.Valid
 }
+
+// scanTypeOf returns the Go type database/sql would scan a column of the given database type into
+func scanTypeOf(databaseTypeName string, nullable bool) reflect.Type {
+	name := strings.ToLower(databaseTypeName)
+	if i := strings.IndexAny(name, "( "); i >= 0 {
+		name = name[:i]
+	}
+
+	var valueType, nullType reflect.Type
+	switch name {
+	case "bool", "boolean", "bit":
+		valueType, nullType = reflect.TypeOf(false), reflect.TypeOf(sql.NullBool{})
+	case "int", "integer", "tinyint", "smallint", "mediumint", "bigint", "int2", "int4", "int8",
+		"serial", "smallserial", "bigserial", "serial2", "serial4", "serial8":
+		valueType, nullType = reflect.TypeOf(int64(0)), reflect.TypeOf(sql.NullInt64{})
+	case "float", "float4", "float8", "double", "real", "numeric", "decimal", "money", "smallmoney":
+		valueType, nullType = reflect.TypeOf(float64(0)), reflect.TypeOf(sql.NullFloat64{})
+	case "date", "datetime", "time", "timetz", "timestamp", "timestamptz",
+		"datetime2", "datetimeoffset", "smalldatetime":
+		valueType, nullType = reflect.TypeOf(time.Time{}), reflect.TypeOf(sql.NullTime{})
+	case "bytea", "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary", "image":
+		return reflect.TypeOf([]byte(nil))
+	default:
+		valueType, nullType = reflect.TypeOf(""), reflect.TypeOf(sql.NullString{})
+	}
+
+	if nullable {
+		return nullType
+	}
+	return valueType
+}
+
+// ColumnChanges records which attributes of a column differ from its model field
+type ColumnChanges struct {
+	// Type is set when the data type, size or precision changed
+	Type     bool
+	Nullable bool
+	Unique   bool
+	Default  bool
+	Comment  bool
+}
+
+// Any reports whether any attribute changed
+func (c ColumnChanges) Any() bool {
+	return c.Type || c.Nullable || c.Unique || c.Default || c.Comment
+}
+
+func (c ColumnChanges) String() string {
+	var attributes []string
+	for _, attribute := range []struct {
+		changed bool
+		name    string
+	}{{c.Type, "type"}, {c.Nullable, "nullable"}, {c.Unique, "unique"}, {c.Default, "default"}, {c.Comment, "comment"}} {
+		if attribute.changed {
+			attributes = append(attributes, attribute.name)
+		}
+	}
+	return strings.Join(attributes, ", ")
+}
+
+// fieldDefault returns the default value field declares, empty when it has none
+func fieldDefault(field *schema.Field) string {
+	if !field.HasDefaultValue || field.DefaultValue == "(-)" || strings.EqualFold(field.DefaultValue, "null") {
+		return ""
+	}
+	return field.DefaultValue
+}
+
+// columnTypeOf describes the column field declares the way the catalog would report it
+func (m *Migrator) columnTypeOf(field *schema.Field) ColumnType {
+	return m.snapshotColumnOf(field).columnType()
+}
+
+// snapshotColumnOf describes the column field declares the way a Snapshot records it
+func (m *Migrator) snapshotColumnOf(field *schema.Field) SnapshotColumn {
+	return SnapshotColumn{
+		Name: field.DBName,
+		// auto increment is an attribute of its own, not part of the type
+		Type:          regAutoIncrement.ReplaceAllString(m.DataTypeOf(field), ""),
+		PrimaryKey:    field.PrimaryKey,
+		AutoIncrement: field.AutoIncrement,
+		Unique:        field.Unique,
+		Nullable:      !field.NotNull && !field.PrimaryKey,
+		Default:       fieldDefault(field),
+		Comment:       field.Comment,
+	}
+}
+
+// columnType describes the snapshot column the way the catalog would report it
+func (c SnapshotColumn) columnType() ColumnType {
+	column := ColumnType{
+		NameValue:          sql.NullString{String: c.Name, Valid: true},
+		DataTypeValue:      sql.NullString{String: c.Type, Valid: true},
+		ColumnTypeValue:    sql.NullString{String: c.Type, Valid: true},
+		PrimaryKeyValue:    sql.NullBool{Bool: c.PrimaryKey, Valid: true},
+		UniqueValue:        sql.NullBool{Bool: c.Unique, Valid: true},
+		AutoIncrementValue: sql.NullBool{Bool: c.AutoIncrement, Valid: true},
+		NullableValue:      sql.NullBool{Bool: c.Nullable, Valid: true},
+		CommentValue:       sql.NullString{String: c.Comment, Valid: true},
+		ScanTypeValue:      scanTypeOf(c.Type, c.Nullable),
+	}
+	if i := strings.IndexAny(c.Type, "( "); i >= 0 {
+		column.DataTypeValue.String = c.Type[:i]
+	}
+	if size, scale, ok := parseTypeArgs(c.Type); ok {
+		if scanTypeOf(c.Type, false) == reflect.TypeOf(float64(0)) {
+			column.DecimalSizeValue = sql.NullInt64{Int64: size, Valid: true}
+			column.ScaleValue = sql.NullInt64{Int64: scale, Valid: true}
+		} else {
+			column.LengthValue = sql.NullInt64{Int64: size, Valid: true}
+		}
+	}
+	if c.Default != "" {
+		column.DefaultValueValue = sql.NullString{String: c.Default, Valid: true}
+	}
+	return column
+}
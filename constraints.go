@@ -0,0 +1,188 @@
+package migrator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// foreignKeyOf converts a constraint gorm parsed from a relationship into the shape the catalog reports
+func foreignKeyOf(table string, constraint *schema.Constraint) ForeignKey {
+	fk := ForeignKey{
+		Name:            constraint.Name,
+		Table:           table,
+		ReferencedTable: constraint.ReferenceSchema.Table,
+		OnDelete:        strings.ToUpper(constraint.OnDelete),
+		OnUpdate:        strings.ToUpper(constraint.OnUpdate),
+	}
+	for _, field := range constraint.ForeignKeys {
+		fk.Columns = append(fk.Columns, field.DBName)
+	}
+	for _, field := range constraint.References {
+		fk.ReferencedColumns = append(fk.ReferencedColumns, field.DBName)
+	}
+	return fk
+}
+
+// modelForeignKeys returns the foreign keys the relationships of stmt's model declare on its own table,
+// the ones CreateTable would create, by name
+func (m *Migrator) modelForeignKeys(stmt *gorm.Statement) map[string]ForeignKey {
+	foreignKeys := map[string]ForeignKey{}
+	if m.DB.DisableForeignKeyConstraintWhenMigrating {
+		return foreignKeys
+	}
+	for _, rel := range stmt.Schema.Relationships.Relations {
+		if constraint := rel.ParseConstraint(); constraint != nil && constraint.Schema == stmt.Schema {
+			foreignKeys[constraint.Name] = foreignKeyOf(stmt.Table, constraint)
+		}
+	}
+	return foreignKeys
+}
+
+// referentialAction normalizes an ON DELETE or ON UPDATE action, an unspecified action is NO ACTION
+func referentialAction(action string) string {
+	action = strings.Join(strings.Fields(strings.ToUpper(action)), " ")
+	if action == "" {
+		return "NO ACTION"
+	}
+	return action
+}
+
+// matchForeignKey finds the model foreign key a live one corresponds to, by the names GuessConstraintAndTable
+// resolves or, for a foreign key declared without a name, by its columns
+func (m *Migrator) matchForeignKey(stmt *gorm.Statement, live ForeignKey, modelForeignKeys map[string]ForeignKey) (ForeignKey, bool) {
+	if live.Name == "" {
+		for _, fk := range modelForeignKeys {
+			if strings.Join(fk.Columns, ",") == strings.Join(live.Columns, ",") && fk.ReferencedTable == live.ReferencedTable {
+				return fk, true
+			}
+		}
+		return ForeignKey{}, false
+	}
+	if constraint, _, _ := m.GuessConstraintAndTable(stmt, live.Name); constraint != nil {
+		fk, ok := modelForeignKeys[constraint.Name]
+		return fk, ok
+	}
+	return ForeignKey{}, false
+}
+
+// foreignKeyChanges describes how the model foreign key fk differs from the live one, nothing when they match
+func foreignKeyChanges(live, fk ForeignKey) []string {
+	var changes []string
+	if live.Name != "" && live.Name != fk.Name {
+		changes = append(changes, fmt.Sprintf("name %s to %s", live.Name, fk.Name))
+	}
+	if strings.Join(live.Columns, ",") != strings.Join(fk.Columns, ",") {
+		changes = append(changes, fmt.Sprintf("columns (%s) to (%s)", strings.Join(live.Columns, ", "), strings.Join(fk.Columns, ", ")))
+	}
+	if live.ReferencedTable != fk.ReferencedTable || strings.Join(live.ReferencedColumns, ",") != strings.Join(fk.ReferencedColumns, ",") {
+		changes = append(changes, fmt.Sprintf("references %s(%s) to %s(%s)",
+			live.ReferencedTable, strings.Join(live.ReferencedColumns, ", "), fk.ReferencedTable, strings.Join(fk.ReferencedColumns, ", ")))
+	}
+	if from, to := referentialAction(live.OnDelete), referentialAction(fk.OnDelete); from != to {
+		changes = append(changes, fmt.Sprintf("on delete %s to %s", from, to))
+	}
+	if from, to := referentialAction(live.OnUpdate), referentialAction(fk.OnUpdate); from != to {
+		changes = append(changes, fmt.Sprintf("on update %s to %s", from, to))
+	}
+	return changes
+}
+
+// planForeignKeys returns the operations that drop the live foreign keys of stmt's table that its model no
+// longer declares or declares differently, and those that add the new and changed ones. dropped records the
+// foreign keys dropped, so dropping a column does not drop them again.
+func (m *Migrator) planForeignKeys(stmt *gorm.Statement, dependents *tableDependents, dropped map[string]bool) (dropOps, addOps []Operation) {
+	modelForeignKeys := m.modelForeignKeys(stmt)
+	replaced := map[string]ForeignKey{}
+	matched := map[string]bool{}
+
+	for _, live := range dependents.foreignKeys {
+		if fk, ok := m.matchForeignKey(stmt, live, modelForeignKeys); ok && !matched[fk.Name] {
+			matched[fk.Name] = true
+			if len(foreignKeyChanges(live, fk)) == 0 {
+				continue
+			}
+			replaced[fk.Name] = live
+		}
+		dropped["fk:"+live.Name] = true
+		dropOps = append(dropOps, &DropForeignKey{Table: stmt.Table, ForeignKey: live})
+	}
+
+	names := make([]string, 0, len(modelForeignKeys))
+	for name := range modelForeignKeys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if live, ok := replaced[name]; ok {
+			live := live
+			addOps = append(addOps, &AddForeignKey{Table: stmt.Table, ForeignKey: modelForeignKeys[name], Replaces: &live})
+		} else if !matched[name] {
+			addOps = append(addOps, &AddForeignKey{Table: stmt.Table, ForeignKey: modelForeignKeys[name]})
+		}
+	}
+	return dropOps, addOps
+}
+
+// modelChecks returns the check constraints stmt's model declares, the ones CreateTable would create, by name
+func modelChecks(stmt *gorm.Statement) map[string]Check {
+	checks := map[string]Check{}
+	for name, chk := range stmt.Schema.ParseCheckConstraints() {
+		checks[name] = Check{Name: chk.Name, Table: stmt.Table, Expression: chk.Constraint}
+	}
+	return checks
+}
+
+// checkChanges describes how the model check chk differs from the live one, nothing when they match
+func checkChanges(live, chk Check) []string {
+	var changes []string
+	if live.Name != chk.Name {
+		changes = append(changes, fmt.Sprintf("name %s to %s", live.Name, chk.Name))
+	}
+	if normalizeExpression(live.Expression) != normalizeExpression(chk.Expression) {
+		changes = append(changes, fmt.Sprintf("expression %q to %q", live.Expression, chk.Expression))
+	}
+	return changes
+}
+
+// planChecks returns the operations that drop the live check constraints of stmt's table that its model no
+// longer declares or declares differently, and those that add the new and changed ones. Live checks are
+// matched to the model by the names GuessConstraintAndTable resolves. dropped records the checks dropped,
+// so dropping a column does not drop them again.
+func (m *Migrator) planChecks(stmt *gorm.Statement, dependents *tableDependents, dropped map[string]bool) (dropOps, addOps []Operation) {
+	checks := modelChecks(stmt)
+	replaced := map[string]Check{}
+	matched := map[string]bool{}
+
+	for _, live := range dependents.checks {
+		if _, guessed, _ := m.GuessConstraintAndTable(stmt, live.Name); guessed != nil {
+			if chk, ok := checks[guessed.Name]; ok && !matched[chk.Name] {
+				matched[chk.Name] = true
+				if len(checkChanges(live, chk)) == 0 {
+					continue
+				}
+				replaced[chk.Name] = live
+			}
+		}
+		dropped["check:"+live.Name] = true
+		dropOps = append(dropOps, &DropCheck{Table: stmt.Table, Check: live})
+	}
+
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if live, ok := replaced[name]; ok {
+			live := live
+			addOps = append(addOps, &AddCheck{Table: stmt.Table, Check: checks[name], Replaces: &live})
+		} else if !matched[name] {
+			addOps = append(addOps, &AddCheck{Table: stmt.Table, Check: checks[name]})
+		}
+	}
+	return dropOps, addOps
+}
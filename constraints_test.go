@@ -0,0 +1,265 @@
+package migrator
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/postgres"
+)
+
+type planBookCascade struct {
+	ID       uint
+	Price    float64 `gorm:"type:decimal(10,2)"`
+	AuthorID uint
+	Author   planAuthor `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (planBookCascade) TableName() string { return "plan_books" }
+
+// planAgainstSnapshot returns the Postgres plan of the after models against the snapshot the before models
+// leave behind, once edit changed its tables the way the catalog would describe them
+func planAgainstSnapshot(t *testing.T, before, after []interface{}, edit func(table *SnapshotTable)) *Plan {
+	t.Helper()
+	m := offlineMigrator(t, postgres.Open("host=localhost"))
+	m.Models = before
+	mustRun(t, m, "create", "before")
+
+	if edit != nil {
+		paths, err := filepath.Glob(filepath.Join(m.migrationPath, "*.snapshot.json"))
+		if err != nil || len(paths) != 1 {
+			t.Fatalf("snapshots = %v, %v, want one", paths, err)
+		}
+		snapshot, err := m.latestSnapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range snapshot.Tables {
+			edit(&snapshot.Tables[i])
+		}
+		if err := writeSnapshot(paths[0], snapshot); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m.Models = after
+	var plan *Plan
+	if err := m.withTarget(func(target *Migrator) (err error) {
+		plan, err = target.Plan()
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return plan
+}
+
+func TestPlanForeignKeys(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after []interface{}
+		edit          func(table *SnapshotTable)
+		changes       []string
+	}{
+		{
+			name:    "add",
+			before:  []interface{}{&planAuthor{}, &planBook{}},
+			after:   []interface{}{&planAuthor{}, &planBookWithAuthor{}},
+			changes: []string{"add foreign key plan_books (fk_plan_books_author)"},
+		},
+		{
+			name:    "drop",
+			before:  []interface{}{&planAuthor{}, &planBookWithAuthor{}},
+			after:   []interface{}{&planAuthor{}, &planBook{}},
+			changes: []string{"drop foreign key plan_books (fk_plan_books_author)"},
+		},
+		{
+			name:   "replace",
+			before: []interface{}{&planAuthor{}, &planBookWithAuthor{}},
+			after:  []interface{}{&planAuthor{}, &planBookCascade{}},
+			changes: []string{
+				"drop foreign key plan_books (fk_plan_books_author)",
+				"recreate foreign key plan_books (fk_plan_books_author: on delete NO ACTION to CASCADE)",
+			},
+		},
+		{
+			// catalogs report the default actions the model leaves out
+			name:   "unchanged with the default actions spelled out",
+			before: []interface{}{&planAuthor{}, &planBookWithAuthor{}},
+			after:  []interface{}{&planAuthor{}, &planBookWithAuthor{}},
+			edit: func(table *SnapshotTable) {
+				for i := range table.ForeignKeys {
+					table.ForeignKeys[i].OnDelete, table.ForeignKeys[i].OnUpdate = "NO ACTION", "NO ACTION"
+				}
+			},
+		},
+		{
+			// SQLite keeps foreign keys declared without a name, they are matched by their columns
+			name:   "unchanged without a name",
+			before: []interface{}{&planAuthor{}, &planBookWithAuthor{}},
+			after:  []interface{}{&planAuthor{}, &planBookWithAuthor{}},
+			edit: func(table *SnapshotTable) {
+				for i := range table.ForeignKeys {
+					table.ForeignKeys[i].Name = ""
+				}
+			},
+		},
+		{
+			name:   "replace a changed reference",
+			before: []interface{}{&planAuthor{}, &planBookWithAuthor{}},
+			after:  []interface{}{&planAuthor{}, &planBookWithAuthor{}},
+			edit: func(table *SnapshotTable) {
+				for i := range table.ForeignKeys {
+					table.ForeignKeys[i].ReferencedTable = "plan_writers"
+				}
+			},
+			changes: []string{
+				"drop foreign key plan_books (fk_plan_books_author)",
+				"recreate foreign key plan_books (fk_plan_books_author: references plan_writers(id) to plan_authors(id))",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertChanges(t, planAgainstSnapshot(t, tt.before, tt.after, tt.edit), tt.changes...)
+		})
+	}
+}
+
+func TestNormalizeExpression(t *testing.T) {
+	// each catalog expression is how the database reports the check declared as model
+	tests := []struct {
+		dialect, catalog, model string
+	}{
+		{"postgres", "(price > (0)::numeric)", "price > 0"},
+		{"postgres", "((price >= (0)::numeric) AND (price < (1000)::numeric))", "price >= 0 AND price < 1000"},
+		{"postgres", "((name)::text <> ''::text)", "name <> ''"},
+		{"postgres", "(length((code)::text) = 3)", "length(code) = 3"},
+		{"postgres", "(created_at > '2020-01-01 00:00:00+00'::timestamp with time zone)", "created_at > '2020-01-01 00:00:00+00'"},
+		{"postgres", "((tags)::text[] <> '{}'::text[])", "tags <> '{}'"},
+		{"postgres", "((\"Order\" > 0))", "\"Order\" > 0"},
+		{"mysql", "(`price` > 0)", "price > 0"},
+		{"mysql", "((`price` >= 0) and (`price` < 1000))", "price >= 0 AND price < 1000"},
+		{"mysql", "(`name` <> _utf8mb4'')", "name <> ''"},
+		{"mysql", "(`status` in (_utf8mb4'draft',_utf8mb4'published'))", "status IN ('draft', 'published')"},
+		{"mysql", "(char_length(`code`) = 3)", "CHAR_LENGTH(code) = 3"},
+		{"mariadb", "`price` > 0", "price > 0"},
+		{"sqlite", "price > 0", "price > 0"},
+		{"sqlite", "\"price\" >= 0 AND \"price\" < 1000", "price >= 0 AND price < 1000"},
+		{"sqlserver", "([price]>(0))", "price > 0"},
+		{"sqlserver", "([name]<>'')", "name <> ''"},
+		{"sqlserver", "(len([code])=(3))", "len(code) = 3"},
+	}
+	for _, tt := range tests {
+		if got, want := normalizeExpression(tt.catalog), normalizeExpression(tt.model); got != want {
+			t.Errorf("%s: normalizeExpression(%q) = %q, want %q like %q", tt.dialect, tt.catalog, got, want, tt.model)
+		}
+	}
+
+	// expressions that differ are never taken for one another
+	for _, pair := range [][2]string{
+		{"price > 0", "price >= 0"},
+		{"status = 'draft'", "status = 'published'"},
+		{"length(code) = 3", "length(code) = 4"},
+		{"code <> '_x'", "code <> ''"},
+		{"price > 0", "cost > 0"},
+	} {
+		if normalizeExpression(pair[0]) == normalizeExpression(pair[1]) {
+			t.Errorf("normalizeExpression(%q) = normalizeExpression(%q)", pair[0], pair[1])
+		}
+	}
+}
+
+func TestPlanChecks(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after []interface{}
+		edit          func(table *SnapshotTable)
+		changes       []string
+	}{
+		{
+			name:    "add",
+			before:  []interface{}{&planBook{}},
+			after:   []interface{}{&planBookChecked{}},
+			changes: []string{"add check plan_books (chk_plan_books_price)"},
+		},
+		{
+			name:    "drop",
+			before:  []interface{}{&planBookChecked{}},
+			after:   []interface{}{&planBook{}},
+			changes: []string{"drop check plan_books (chk_plan_books_price)"},
+		},
+		{
+			name:   "replace",
+			before: []interface{}{&planBookChecked{}},
+			after:  []interface{}{&planBookCheckedAtLeastZero{}},
+			changes: []string{
+				"drop check plan_books (chk_plan_books_price)",
+				`recreate check plan_books (chk_plan_books_price: expression "price > 0" to "price >= 0")`,
+			},
+		},
+		{
+			// pg_get_expr adds parentheses and casts to the expression the model declares
+			name:   "unchanged as Postgres reformats it",
+			before: []interface{}{&planBookChecked{}},
+			after:  []interface{}{&planBookChecked{}},
+			edit: func(table *SnapshotTable) {
+				for i := range table.Checks {
+					table.Checks[i].Expression = "(price > (0)::numeric)"
+				}
+			},
+		},
+		{
+			name:   "replace what Postgres reformats",
+			before: []interface{}{&planBookChecked{}},
+			after:  []interface{}{&planBookCheckedAtLeastZero{}},
+			edit: func(table *SnapshotTable) {
+				for i := range table.Checks {
+					table.Checks[i].Expression = "(price > (0)::numeric)"
+				}
+			},
+			changes: []string{
+				"drop check plan_books (chk_plan_books_price)",
+				`recreate check plan_books (chk_plan_books_price: expression "(price > (0)::numeric)" to "price >= 0")`,
+			},
+		},
+		{
+			// a check the model does not name the way GuessConstraintAndTable resolves is not the model's
+			name:   "drop a check of another name",
+			before: []interface{}{&planBookChecked{}},
+			after:  []interface{}{&planBookChecked{}},
+			edit: func(table *SnapshotTable) {
+				for i := range table.Checks {
+					table.Checks[i].Name = "plan_books_price_check"
+				}
+			},
+			changes: []string{
+				"drop check plan_books (plan_books_price_check)",
+				"add check plan_books (chk_plan_books_price)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertChanges(t, planAgainstSnapshot(t, tt.before, tt.after, tt.edit), tt.changes...)
+		})
+	}
+}
+
+func TestMariaDBJSONCheck(t *testing.T) {
+	tests := []struct {
+		chk  Check
+		want bool
+	}{
+		{Check{Name: "payload", Expression: "json_valid(`payload`)"}, true},
+		{Check{Name: "payload", Expression: "JSON_VALID(`payload`)"}, true},
+		{Check{Name: "payload", Expression: "json_valid(`other`)"}, false},
+		{Check{Name: "chk_events_payload", Expression: "json_valid(`payload`)"}, false},
+		{Check{Name: "payload", Expression: "`payload` <> ''"}, false},
+	}
+	for _, tt := range tests {
+		if got := mariadbJSONCheck(tt.chk); got != tt.want {
+			t.Errorf("mariadbJSONCheck(%s, %q) = %v, want %v", tt.chk.Name, tt.chk.Expression, got, tt.want)
+		}
+	}
+}
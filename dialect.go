@@ -0,0 +1,241 @@
+package migrator
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	regTypeArgs    = regexp.MustCompile(`\(\s*(\d+)\s*(?:,\s*(\d+)\s*)?\)`)
+	regTypeArgsAll = regexp.MustCompile(`\([^)]*\)`)
+)
+
+// Dialect reads the live schema from a database's catalog and renders the DDL that changes it. The built-in
+// dialects are chosen by gorm.Dialector name, RegisterDialect adds others.
+type Dialect interface {
+	// MigrateDriver returns the golang-migrate driver that applies migrations to db
+	MigrateDriver(db *sql.DB) (database.Driver, error)
+	// NormalizeType reduces a column type to the name the database knows it by, without its length,
+	// precision or attributes, so aliases like integer and int4 compare equal
+	NormalizeType(columnType string) string
+
+	// CurrentDatabase returns the name of the connected database
+	CurrentDatabase(m *Migrator) (string, error)
+	// HasTable reports whether stmt's table exists as a base table
+	HasTable(m *Migrator, stmt *gorm.Statement) (bool, error)
+	// Tables returns the base tables in the schema of stmt's table
+	Tables(m *Migrator, stmt *gorm.Statement) ([]string, error)
+	// HasIndex reports whether stmt's table has an index called name
+	HasIndex(m *Migrator, stmt *gorm.Statement, name string) (bool, error)
+	// ColumnTypes returns the columns of stmt's table in ordinal order without reading any rows
+	ColumnTypes(m *Migrator, stmt *gorm.Statement) ([]ColumnType, error)
+	// Indexes returns the indexes of stmt's table, including those backing constraints
+	Indexes(m *Migrator, stmt *gorm.Statement) ([]Index, error)
+	// ForeignKeys returns the foreign keys declared on stmt's table
+	ForeignKeys(m *Migrator, stmt *gorm.Statement) ([]ForeignKey, error)
+	// Checks returns the check constraints declared on stmt's table
+	Checks(m *Migrator, stmt *gorm.Statement) ([]Check, error)
+
+	// ColumnDefinition renders column's type and attributes the way CREATE TABLE declares them
+	ColumnDefinition(m *Migrator, column ColumnType) string
+	// ColumnCommentSQL returns the statement setting column's comment when it cannot be declared inline
+	ColumnCommentSQL(m *Migrator, stmt *gorm.Statement, column ColumnType) string
+	// AddColumnSQL returns the statements that add column to stmt's table as the catalog described it
+	AddColumnSQL(m *Migrator, stmt *gorm.Statement, column ColumnType) string
+	// DropColumnSQL returns the statements that drop the column name from stmt's table
+	DropColumnSQL(m *Migrator, stmt *gorm.Statement, name string) string
+	// RenameTableSQL returns the statement that renames stmt's table to name
+	RenameTableSQL(m *Migrator, stmt *gorm.Statement, name string) string
+	// RenameColumnSQL returns the statement that renames column of stmt's table to name, keeping its data
+	RenameColumnSQL(m *Migrator, stmt *gorm.Statement, column ColumnType, name string) string
+	// AlterColumnSQL returns the statements that change the attributes listed in changes of the column of
+	// stmt's table to those column describes
+	AlterColumnSQL(m *Migrator, stmt *gorm.Statement, column ColumnType, changes ColumnChanges) string
+	// CreateIndexSQL returns the statements that create the model index idx on stmt's table
+	CreateIndexSQL(m *Migrator, stmt *gorm.Statement, idx *schema.Index) string
+	// DropIndexSQL returns the statement that drops idx from stmt's table
+	DropIndexSQL(m *Migrator, stmt *gorm.Statement, idx Index) string
+	// AddForeignKeySQL returns the statement that adds fk to stmt's table
+	AddForeignKeySQL(m *Migrator, stmt *gorm.Statement, fk ForeignKey) string
+	// DropForeignKeySQL returns the statement that drops fk from stmt's table
+	DropForeignKeySQL(m *Migrator, stmt *gorm.Statement, fk ForeignKey) string
+	// AddCheckSQL returns the statement that adds chk to stmt's table
+	AddCheckSQL(m *Migrator, stmt *gorm.Statement, chk Check) string
+	// DropCheckSQL returns the statement that drops chk from stmt's table
+	DropCheckSQL(m *Migrator, stmt *gorm.Statement, chk Check) string
+	// BeginSQL returns the statement that opens the transaction a migration runs in
+	BeginSQL() string
+}
+
+var (
+	dialectsMu sync.RWMutex
+	// dialects maps gorm.Dialector names to their dialect
+	dialects = map[string]Dialect{
+		"postgres":  postgresDialect{},
+		"mysql":     mysqlDialect{},
+		"sqlite":    sqliteDialect{},
+		"sqlserver": sqlserverDialect{},
+	}
+)
+
+// RegisterDialect makes d the dialect of databases whose gorm.Dialector is called name, replacing any
+// built-in one
+func RegisterDialect(name string, d Dialect) {
+	dialectsMu.Lock()
+	defer dialectsMu.Unlock()
+	dialects[name] = d
+}
+
+// LookupDialect returns the dialect registered for the gorm.Dialector called name, a custom dialect can
+// embed it to override only what differs
+func LookupDialect(name string) (Dialect, bool) {
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+	d, ok := dialects[name]
+	return d, ok
+}
+
+// dialect returns the dialect of the migrator's database, Config.Dialect when set
+func (m *Migrator) dialect() (Dialect, error) {
+	return m.dialectOf(m.DB)
+}
+
+// dialectOf returns the dialect of db, Config.Dialect when db shares the gorm.Dialector of the migrator's
+// database
+func (m *Migrator) dialectOf(db *gorm.DB) (Dialect, error) {
+	name := db.Dialector.Name()
+	if m.Dialect != nil && name == m.DB.Dialector.Name() {
+		return m.Dialect, nil
+	}
+	if d, ok := LookupDialect(name); ok {
+		return d, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedDialect, name)
+}
+
+// parseTypeArgs returns the length or precision and scale declared in a column type like `decimal(10,2)`
+func parseTypeArgs(columnType string) (size int64, scale int64, ok bool) {
+	matches := regTypeArgs.FindStringSubmatch(columnType)
+	if matches == nil {
+		return 0, 0, false
+	}
+	size, _ = strconv.ParseInt(matches[1], 10, 64)
+	if matches[2] != "" {
+		scale, _ = strconv.ParseInt(matches[2], 10, 64)
+	}
+	return size, scale, true
+}
+
+// baseType lowercases columnType and strips its arguments and auto increment, `character varying(64)` is
+// `character varying`
+func baseType(columnType string) string {
+	columnType = regAutoIncrement.ReplaceAllString(columnType, "")
+	columnType = regTypeArgsAll.ReplaceAllString(strings.ToLower(columnType), "")
+	return strings.Join(strings.Fields(columnType), " ")
+}
+
+// normalizeAlias maps the base type of columnType through aliases
+func normalizeAlias(columnType string, aliases map[string]string) string {
+	columnType = baseType(columnType)
+	if alias, ok := aliases[columnType]; ok {
+		return alias
+	}
+	return columnType
+}
+
+// unquoteDefault strips the quotes around a string literal default value, the way gorm
+// strips them from the `default` tag
+func unquoteDefault(value string) string {
+	if len(value) < 2 {
+		return value
+	}
+	if quote := value[0]; (quote == '\'' || quote == '"') && value[len(value)-1] == quote {
+		return strings.ReplaceAll(value[1:len(value)-1], string([]byte{quote, quote}), string(quote))
+	}
+	return value
+}
+
+// defaultLiteral renders the default value of column as SQL, quoting string literals the catalog unquoted
+func defaultLiteral(column ColumnType) (string, bool) {
+	value, ok := column.DefaultValue()
+	if !ok || strings.EqualFold(value, "null") {
+		return "", false
+	}
+	if t := column.ScanType(); (t == reflect.TypeOf("") || t == reflect.TypeOf(sql.NullString{})) && !strings.Contains(value, "(") {
+		return quoteString(value), true
+	}
+	return value, true
+}
+
+// columnList converts column names to a parenthesized column list
+func columnList(names []string) []interface{} {
+	columns := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		columns = append(columns, clause.Column{Name: name})
+	}
+	return columns
+}
+
+// addColumnSQL renders `ALTER TABLE ... ADD` from the dialect's column definition and comment
+func addColumnSQL(m *Migrator, stmt *gorm.Statement, d Dialect, column ColumnType) string {
+	return buildRawSQL(m.DB, "ALTER TABLE ? ADD ? ?", m.CurrentTable(stmt), clause.Column{Name: column.Name()}, clause.Expr{SQL: d.ColumnDefinition(m, column)}) +
+		d.ColumnCommentSQL(m, stmt, column)
+}
+
+// dropColumnSQL renders `ALTER TABLE ... DROP COLUMN`, shared by dialects that need nothing dropped first
+func dropColumnSQL(m *Migrator, stmt *gorm.Statement, name string) string {
+	return buildRawSQL(m.DB, "ALTER TABLE ? DROP COLUMN ?", m.CurrentTable(stmt), clause.Column{Name: name})
+}
+
+// renameTableSQL renders `ALTER TABLE ... RENAME TO`, shared by dialects that support it
+func renameTableSQL(m *Migrator, stmt *gorm.Statement, name string) string {
+	return buildRawSQL(m.DB, "ALTER TABLE ? RENAME TO ?", m.CurrentTable(stmt), clause.Table{Name: name})
+}
+
+// unqualifiedTable returns table without its schema, the table keeps its schema when renamed
+func unqualifiedTable(table string) string {
+	return table[strings.LastIndex(table, ".")+1:]
+}
+
+// renameColumnSQL renders `ALTER TABLE ... RENAME COLUMN`, shared by dialects that support it
+func renameColumnSQL(m *Migrator, stmt *gorm.Statement, from, to string) string {
+	return buildRawSQL(m.DB, "ALTER TABLE ? RENAME COLUMN ? TO ?", m.CurrentTable(stmt), clause.Column{Name: from}, clause.Column{Name: to})
+}
+
+// foreignKeyClause renders fk the way CREATE TABLE and ADD CONSTRAINT declare it
+func foreignKeyClause(fk ForeignKey) (string, []interface{}) {
+	sql := "FOREIGN KEY ? REFERENCES ??"
+	values := []interface{}{columnList(fk.Columns), clause.Table{Name: fk.ReferencedTable}, columnList(fk.ReferencedColumns)}
+	if fk.Name != "" {
+		sql = "CONSTRAINT ? " + sql
+		values = append([]interface{}{clause.Column{Name: fk.Name}}, values...)
+	}
+	if fk.OnDelete != "" && fk.OnDelete != "NO ACTION" {
+		sql += " ON DELETE " + fk.OnDelete
+	}
+	if fk.OnUpdate != "" && fk.OnUpdate != "NO ACTION" {
+		sql += " ON UPDATE " + fk.OnUpdate
+	}
+	return sql, values
+}
+
+// addForeignKeySQL renders `ALTER TABLE ... ADD CONSTRAINT ... FOREIGN KEY`, shared by dialects that support it
+func addForeignKeySQL(m *Migrator, stmt *gorm.Statement, fk ForeignKey) string {
+	sql, values := foreignKeyClause(fk)
+	return buildRawSQL(m.DB, "ALTER TABLE ? ADD "+sql, append([]interface{}{m.CurrentTable(stmt)}, values...)...)
+}
+
+// addCheckSQL renders `ALTER TABLE ... ADD CONSTRAINT ... CHECK`, shared by dialects that support it
+func addCheckSQL(m *Migrator, stmt *gorm.Statement, chk Check) string {
+	return buildRawSQL(m.DB, "ALTER TABLE ? ADD CONSTRAINT ? CHECK (?)", m.CurrentTable(stmt), clause.Column{Name: chk.Name}, clause.Expr{SQL: chk.Expression})
+}
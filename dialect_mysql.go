@@ -0,0 +1,449 @@
+package migrator
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+type mysqlDialect struct{}
+
+// schemaAndTable splits a `database.table` name, defaulting to the connection's database
+func (mysqlDialect) schemaAndTable(stmt *gorm.Statement) (interface{}, string) {
+	if tables := strings.Split(stmt.Table, "."); len(tables) == 2 {
+		return tables[0], tables[1]
+	}
+	return clause.Expr{SQL: "DATABASE()"}, stmt.Table
+}
+
+func (mysqlDialect) CurrentDatabase(m *Migrator) (name string, err error) {
+	err = m.DB.Raw("SELECT DATABASE()").Row().Scan(&name)
+	return
+}
+
+func (d mysqlDialect) HasTable(m *Migrator, stmt *gorm.Statement) (bool, error) {
+	var count int64
+	currentDatabase, table := d.schemaAndTable(stmt)
+	err := m.DB.Raw("SELECT count(*) FROM information_schema.tables WHERE table_schema = ? AND table_name = ? AND table_type = 'BASE TABLE'", currentDatabase, table).Row().Scan(&count)
+	return count > 0, err
+}
+
+func (d mysqlDialect) Tables(m *Migrator, stmt *gorm.Statement) (tables []string, err error) {
+	currentDatabase, _ := d.schemaAndTable(stmt)
+	err = m.DB.Raw("SELECT table_name FROM information_schema.tables WHERE table_schema = ? AND table_type = 'BASE TABLE' ORDER BY table_name", currentDatabase).Scan(&tables).Error
+	return
+}
+
+func (d mysqlDialect) HasIndex(m *Migrator, stmt *gorm.Statement, name string) (bool, error) {
+	var count int64
+	currentDatabase, table := d.schemaAndTable(stmt)
+	err := m.DB.Raw("SELECT count(*) FROM information_schema.statistics WHERE table_schema = ? AND table_name = ? AND index_name = ?", currentDatabase, table, name).Row().Scan(&count)
+	return count > 0, err
+}
+
+func (d mysqlDialect) ColumnTypes(m *Migrator, stmt *gorm.Statement) ([]ColumnType, error) {
+	currentDatabase, table := d.schemaAndTable(stmt)
+	rows, err := m.DB.Raw(`SELECT column_name, data_type, column_type, is_nullable = 'YES',
+	character_maximum_length, numeric_precision, numeric_scale, datetime_precision,
+	column_default, column_comment, column_key, extra
+FROM information_schema.columns
+WHERE table_schema = ? AND table_name = ?
+ORDER BY ordinal_position`, currentDatabase, table).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columnTypes []ColumnType
+	for rows.Next() {
+		var (
+			column            ColumnType
+			datetimePrecision sql.NullInt64
+			columnKey         string
+			extra             string
+		)
+		if err := rows.Scan(
+			&column.NameValue, &column.DataTypeValue, &column.ColumnTypeValue, &column.NullableValue,
+			&column.LengthValue, &column.DecimalSizeValue, &column.ScaleValue, &datetimePrecision,
+			&column.DefaultValueValue, &column.CommentValue, &columnKey, &extra,
+		); err != nil {
+			return nil, err
+		}
+
+		if datetimePrecision.Valid {
+			column.DecimalSizeValue = datetimePrecision
+		}
+		if column.DefaultValueValue.Valid {
+			column.DefaultValueValue.String = unquoteDefault(column.DefaultValueValue.String)
+		}
+
+		column.PrimaryKeyValue = sql.NullBool{Bool: columnKey == "PRI", Valid: true}
+		column.UniqueValue = sql.NullBool{Valid: true}
+		column.AutoIncrementValue = sql.NullBool{Bool: strings.Contains(extra, "auto_increment"), Valid: true}
+		column.ScanTypeValue = scanTypeOf(column.DataTypeValue.String, column.NullableValue.Bool)
+		columnTypes = append(columnTypes, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// MariaDB reports a JSON column as the longtext it stands for
+	_, jsonColumns, err := d.checks(m, stmt)
+	if err != nil {
+		return nil, err
+	}
+	for i := range columnTypes {
+		if jsonColumns[columnTypes[i].NameValue.String] && columnTypes[i].DataTypeValue.String == "longtext" {
+			columnTypes[i].DataTypeValue.String, columnTypes[i].ColumnTypeValue.String = "json", "json"
+		}
+	}
+
+	// a `unique` column is backed by a single column unique index named after it,
+	// anything else is a unique index declared on its own
+	indexRows, err := m.DB.Raw(`SELECT index_name, MIN(column_name) FROM information_schema.statistics
+WHERE table_schema = ? AND table_name = ? AND non_unique = 0 AND index_name <> 'PRIMARY'
+GROUP BY index_name HAVING COUNT(*) = 1`, currentDatabase, table).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer indexRows.Close()
+
+	for indexRows.Next() {
+		var indexName, columnName string
+		if err := indexRows.Scan(&indexName, &columnName); err != nil {
+			return nil, err
+		}
+		if indexName != columnName {
+			continue
+		}
+		for i := range columnTypes {
+			if columnTypes[i].NameValue.String == columnName {
+				columnTypes[i].UniqueValue.Bool = true
+			}
+		}
+	}
+
+	return columnTypes, indexRows.Err()
+}
+
+func (d mysqlDialect) Indexes(m *Migrator, stmt *gorm.Statement) ([]Index, error) {
+	currentDatabase, table := d.schemaAndTable(stmt)
+	rows, err := m.DB.Raw(`SELECT index_name, non_unique = 0, column_name, sub_part, collation, index_type
+FROM information_schema.statistics
+WHERE table_schema = ? AND table_name = ?
+ORDER BY index_name, seq_in_index`, currentDatabase, table).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		indexes    []Index
+		parts      [][]string
+		kinds      []string
+		functional []bool
+	)
+	for rows.Next() {
+		var (
+			idx       = Index{Table: stmt.Table}
+			column    sql.NullString
+			subPart   sql.NullInt64
+			collation sql.NullString
+			indexType string
+		)
+		if err := rows.Scan(&idx.Name, &idx.Unique, &column, &subPart, &collation, &indexType); err != nil {
+			return nil, err
+		}
+		if len(indexes) == 0 || indexes[len(indexes)-1].Name != idx.Name {
+			idx.Primary = idx.Name == "PRIMARY"
+			indexes = append(indexes, idx)
+			parts = append(parts, nil)
+			kinds = append(kinds, indexType)
+			functional = append(functional, false)
+		}
+		last := len(indexes) - 1
+
+		// functional key parts have no column and cannot be recreated from the statistics
+		if !column.Valid {
+			functional[last] = true
+			continue
+		}
+		indexes[last].Columns = append(indexes[last].Columns, column.String)
+		part, key := stmt.Quote(column.String), column.String
+		if subPart.Valid {
+			part += fmt.Sprintf("(%d)", subPart.Int64)
+			key += fmt.Sprintf("(%d)", subPart.Int64)
+		}
+		if collation.String == "D" {
+			part += " DESC"
+			key += " DESC"
+		}
+		parts[last] = append(parts[last], part)
+		indexes[last].Keys = append(indexes[last].Keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range indexes {
+		idx := &indexes[i]
+		// columns declared `unique` get a single column unique index named after them
+		idx.Constraint = idx.Primary || (idx.Unique && len(idx.Columns) == 1 && idx.Columns[0] == idx.Name)
+		if functional[i] {
+			idx.Keys = nil
+		}
+		if kinds[i] == "FULLTEXT" || kinds[i] == "SPATIAL" {
+			idx.Class = kinds[i]
+		} else {
+			idx.Method = strings.ToLower(kinds[i])
+		}
+
+		createIndexSQL := "CREATE "
+		switch {
+		case kinds[i] == "FULLTEXT" || kinds[i] == "SPATIAL":
+			createIndexSQL += kinds[i] + " "
+		case idx.Unique:
+			createIndexSQL += "UNIQUE "
+		}
+		createIndexSQL += fmt.Sprintf("INDEX %s ON %s (%s)", stmt.Quote(idx.Name), stmt.Quote(table), strings.Join(parts[i], ","))
+		if kinds[i] == "HASH" {
+			createIndexSQL += " USING HASH"
+		}
+		idx.Definition = createIndexSQL
+	}
+
+	return indexes, nil
+}
+
+func (d mysqlDialect) ForeignKeys(m *Migrator, stmt *gorm.Statement) ([]ForeignKey, error) {
+	currentDatabase, table := d.schemaAndTable(stmt)
+	rows, err := m.DB.Raw(`SELECT k.constraint_name, k.column_name, k.referenced_table_schema = k.table_schema, k.referenced_table_schema,
+	k.referenced_table_name, k.referenced_column_name, r.delete_rule, r.update_rule
+FROM information_schema.key_column_usage k
+JOIN information_schema.referential_constraints r
+	ON r.constraint_schema = k.constraint_schema AND r.constraint_name = k.constraint_name AND r.table_name = k.table_name
+WHERE k.table_schema = ? AND k.table_name = ? AND k.referenced_table_name IS NOT NULL
+ORDER BY k.constraint_name, k.ordinal_position`, currentDatabase, table).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foreignKeys []ForeignKey
+	for rows.Next() {
+		var (
+			fk                       = ForeignKey{Table: stmt.Table}
+			column, referencedColumn string
+			sameSchema               bool
+			referencedSchema         string
+		)
+		if err := rows.Scan(&fk.Name, &column, &sameSchema, &referencedSchema, &fk.ReferencedTable, &referencedColumn, &fk.OnDelete, &fk.OnUpdate); err != nil {
+			return nil, err
+		}
+		if !sameSchema {
+			fk.ReferencedTable = referencedSchema + "." + fk.ReferencedTable
+		}
+		if len(foreignKeys) == 0 || foreignKeys[len(foreignKeys)-1].Name != fk.Name {
+			foreignKeys = append(foreignKeys, fk)
+		}
+		last := &foreignKeys[len(foreignKeys)-1]
+		last.Columns = append(last.Columns, column)
+		last.ReferencedColumns = append(last.ReferencedColumns, referencedColumn)
+	}
+
+	return foreignKeys, rows.Err()
+}
+
+func (d mysqlDialect) Checks(m *Migrator, stmt *gorm.Statement) ([]Check, error) {
+	checks, _, err := d.checks(m, stmt)
+	return checks, err
+}
+
+// checks returns the check constraints declared on stmt's table, and the columns MariaDB declares a check on
+// because they are JSON. MariaDB's JSON is a longtext checked with json_valid, named after the column and
+// never declared by the model, column checks are left out.
+func (d mysqlDialect) checks(m *Migrator, stmt *gorm.Statement) (checks []Check, jsonColumns map[string]bool, err error) {
+	// check constraints are recorded from MySQL 8.0.16 and MariaDB 10.2, MariaDB adds the table they are
+	// declared on and, from 10.5.10, whether they are declared on a column
+	var checkColumns []string
+	if err := m.DB.Raw("SELECT UPPER(column_name) FROM information_schema.columns WHERE table_schema = 'information_schema' AND table_name = 'CHECK_CONSTRAINTS'").Scan(&checkColumns).Error; err != nil || len(checkColumns) == 0 {
+		return nil, nil, err
+	}
+	mariadb, level := containsString(checkColumns, "TABLE_NAME"), "''"
+	if containsString(checkColumns, "LEVEL") {
+		level = "cc.level"
+	}
+
+	query := `SELECT tc.constraint_name, cc.check_clause, ` + level + `
+FROM information_schema.table_constraints tc
+JOIN information_schema.check_constraints cc ON cc.constraint_schema = tc.constraint_schema AND cc.constraint_name = tc.constraint_name
+WHERE tc.constraint_type = 'CHECK' AND tc.table_schema = ? AND tc.table_name = ?`
+	if mariadb {
+		// MariaDB names checks per table
+		query += " AND cc.table_name = tc.table_name"
+	}
+
+	currentDatabase, table := d.schemaAndTable(stmt)
+	rows, err := m.DB.Raw(query+"\nORDER BY tc.constraint_name", currentDatabase, table).Rows()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	jsonColumns = map[string]bool{}
+	for rows.Next() {
+		var (
+			chk        = Check{Table: stmt.Table}
+			checkLevel string
+		)
+		if err := rows.Scan(&chk.Name, &chk.Expression, &checkLevel); err != nil {
+			return nil, nil, err
+		}
+		// before LEVEL, the check of a JSON column is told apart by its name and expression
+		if mariadb && checkLevel != "Table" && mariadbJSONCheck(chk) {
+			jsonColumns[chk.Name] = true
+		} else if checkLevel != "Column" {
+			checks = append(checks, chk)
+		}
+	}
+
+	return checks, jsonColumns, rows.Err()
+}
+
+// mariadbJSONCheck reports whether chk is the check MariaDB declares on a JSON column
+func mariadbJSONCheck(chk Check) bool {
+	return normalizeExpression(chk.Expression) == "json_valid"+strings.ToLower(chk.Name)
+}
+
+func (mysqlDialect) ColumnDefinition(m *Migrator, column ColumnType) string {
+	dataType, _ := column.ColumnType()
+	if nullable, ok := column.Nullable(); ok && !nullable {
+		dataType += " NOT NULL"
+	}
+	if value, ok := defaultLiteral(column); ok {
+		dataType += " DEFAULT " + value
+	}
+	if autoIncrement, _ := column.AutoIncrement(); autoIncrement {
+		dataType += " AUTO_INCREMENT"
+	}
+	if unique, _ := column.Unique(); unique {
+		dataType += " UNIQUE"
+	}
+	if comment, ok := column.Comment(); ok && comment != "" {
+		dataType += " COMMENT " + quoteString(comment)
+	}
+	return dataType
+}
+
+func (mysqlDialect) ColumnCommentSQL(m *Migrator, stmt *gorm.Statement, column ColumnType) string {
+	return ""
+}
+
+func (d mysqlDialect) AddColumnSQL(m *Migrator, stmt *gorm.Statement, column ColumnType) string {
+	return addColumnSQL(m, stmt, d, column)
+}
+
+func (mysqlDialect) DropColumnSQL(m *Migrator, stmt *gorm.Statement, name string) string {
+	return dropColumnSQL(m, stmt, name)
+}
+
+func (mysqlDialect) RenameTableSQL(m *Migrator, stmt *gorm.Statement, name string) string {
+	// the new name says which database the table ends up in
+	return renameTableSQL(m, stmt, name)
+}
+
+func (d mysqlDialect) RenameColumnSQL(m *Migrator, stmt *gorm.Statement, column ColumnType, name string) string {
+	// CHANGE COLUMN works before MySQL 8.0 and on MariaDB, it restates the definition like MODIFY COLUMN
+	definition := column
+	definition.UniqueValue.Bool = false
+	return buildRawSQL(m.DB, "ALTER TABLE ? CHANGE COLUMN ? ? ?", m.CurrentTable(stmt), clause.Column{Name: column.Name()}, clause.Column{Name: name}, clause.Expr{SQL: d.ColumnDefinition(m, definition)})
+}
+
+func (d mysqlDialect) AlterColumnSQL(m *Migrator, stmt *gorm.Statement, column ColumnType, changes ColumnChanges) string {
+	var sql string
+	// MODIFY COLUMN restates the whole definition, but would add another unique index for UNIQUE
+	unique, _ := column.Unique()
+	if changes.Type || changes.Nullable || changes.Default || changes.Comment {
+		definition := column
+		definition.UniqueValue.Bool = false
+		sql += buildRawSQL(m.DB, "ALTER TABLE ? MODIFY COLUMN ? ?", m.CurrentTable(stmt), clause.Column{Name: column.Name()}, clause.Expr{SQL: d.ColumnDefinition(m, definition)})
+	}
+	if changes.Unique {
+		// columns declared `unique` get a single column unique index named after them
+		if unique {
+			sql += buildRawSQL(m.DB, "ALTER TABLE ? ADD UNIQUE INDEX ? (?)", m.CurrentTable(stmt), clause.Column{Name: column.Name()}, clause.Column{Name: column.Name()})
+		} else {
+			sql += d.DropIndexSQL(m, stmt, Index{Name: column.Name()})
+		}
+	}
+	return sql
+}
+
+func (mysqlDialect) CreateIndexSQL(m *Migrator, stmt *gorm.Statement, idx *schema.Index) string {
+	if idx.Comment != "" {
+		commented := *idx
+		commented.Option = strings.TrimSpace("COMMENT " + quoteString(idx.Comment) + " " + idx.Option)
+		idx = &commented
+	}
+	return createIndexSQL(m, stmt, idx, false)
+}
+
+func (mysqlDialect) DropIndexSQL(m *Migrator, stmt *gorm.Statement, idx Index) string {
+	return buildRawSQL(m.DB, "DROP INDEX ? ON ?", clause.Column{Name: idx.Name}, m.CurrentTable(stmt))
+}
+
+func (mysqlDialect) AddForeignKeySQL(m *Migrator, stmt *gorm.Statement, fk ForeignKey) string {
+	return addForeignKeySQL(m, stmt, fk)
+}
+
+func (mysqlDialect) DropForeignKeySQL(m *Migrator, stmt *gorm.Statement, fk ForeignKey) string {
+	return buildRawSQL(m.DB, "ALTER TABLE ? DROP FOREIGN KEY ?", m.CurrentTable(stmt), clause.Column{Name: fk.Name})
+}
+
+func (mysqlDialect) AddCheckSQL(m *Migrator, stmt *gorm.Statement, chk Check) string {
+	return addCheckSQL(m, stmt, chk)
+}
+
+func (mysqlDialect) DropCheckSQL(m *Migrator, stmt *gorm.Statement, chk Check) string {
+	return buildRawSQL(m.DB, "ALTER TABLE ? DROP CHECK ?", m.CurrentTable(stmt), clause.Column{Name: chk.Name})
+}
+
+func (mysqlDialect) BeginSQL() string {
+	return "BEGIN;"
+}
+
+func (mysqlDialect) MigrateDriver(db *sql.DB) (database.Driver, error) {
+	return mysql.WithInstance(db, &mysql.Config{})
+}
+
+// mysqlTypeAliases maps type names to the names information_schema reports
+var mysqlTypeAliases = map[string]string{
+	"boolean":           "tinyint",
+	"bool":              "tinyint",
+	"integer":           "int",
+	"numeric":           "decimal",
+	"dec":               "decimal",
+	"fixed":             "decimal",
+	"real":              "double",
+	"double precision":  "double",
+	"character varying": "varchar",
+	"character":         "char",
+}
+
+// mysqlTypeAttributes are the words that may follow a type name in a column definition
+var mysqlTypeAttributes = map[string]bool{"unsigned": true, "signed": true, "zerofill": true, "null": true, "not": true}
+
+func (mysqlDialect) NormalizeType(columnType string) string {
+	// signedness, zero filling and nullability follow the type name, they are not part of it
+	fields := strings.Fields(baseType(columnType))
+	for len(fields) > 1 && mysqlTypeAttributes[fields[len(fields)-1]] {
+		fields = fields[:len(fields)-1]
+	}
+	return normalizeAlias(strings.Join(fields, " "), mysqlTypeAliases)
+}
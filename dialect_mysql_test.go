@@ -0,0 +1,57 @@
+//go:build integration
+
+package migrator
+
+import (
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// openMySQL opens the database MIGRATOR_TEST_MYSQL_DSN points at, like one started with
+// docker run -e MYSQL_ROOT_PASSWORD=test -e MYSQL_DATABASE=test -p 3306:3306 mysql. The DSN needs
+// multiStatements=true for migrations to run.
+func openMySQL(t *testing.T) *Migrator {
+	db := openIntegration(t, "MIGRATOR_TEST_MYSQL_DSN", mysql.Open)
+	return testMigrator(t, db)
+}
+
+func TestMySQLDialect(t *testing.T) {
+	m := openMySQL(t)
+	var database string
+	if err := m.DB.Raw("SELECT DATABASE()").Row().Scan(&database); err != nil {
+		t.Fatal(err)
+	}
+	testDialectIntrospection(t, m.DB, database)
+}
+
+func TestMySQLAlterColumn(t *testing.T) {
+	testAlterColumnRoundTrip(t, openMySQL(t), &alterItem{}, &alterItemChanged{})
+}
+
+type mysqlEvent struct {
+	ID      uint
+	Kind    string `gorm:"size:32;check:chk_mysql_events_kind,kind <> ''"`
+	Payload string `gorm:"type:json"`
+}
+
+func (mysqlEvent) TableName() string { return "mysql_events" }
+
+func TestMySQLJSONColumnChecks(t *testing.T) {
+	m := openMySQL(t)
+	migrateTo(t, m, &mysqlEvent{})
+	// MariaDB declares a json_valid check on the JSON column, it is not the model's to drop
+	assertNoDrift(t, m)
+
+	var checks []Check
+	if err := m.RunWithValue(&mysqlEvent{}, func(stmt *gorm.Statement) (err error) {
+		checks, err = mysqlDialect{}.Checks(m, stmt)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) != 1 || checks[0].Name != "chk_mysql_events_kind" {
+		t.Errorf("Checks() = %v, want only chk_mysql_events_kind", checks)
+	}
+}
@@ -0,0 +1,440 @@
+package migrator
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var regPostgresCast = regexp.MustCompile(`^(.*)::[\w\s."]+(\[\])?$`)
+
+type postgresDialect struct{}
+
+func (postgresDialect) CurrentDatabase(m *Migrator) (name string, err error) {
+	err = m.DB.Raw("SELECT CURRENT_DATABASE()").Row().Scan(&name)
+	return
+}
+
+func (postgresDialect) HasTable(m *Migrator, stmt *gorm.Statement) (bool, error) {
+	var count int64
+	currentSchema, table := m.CurrentSchema(stmt, stmt.Table)
+	err := m.DB.Raw("SELECT count(*) FROM information_schema.tables WHERE table_schema = ? AND table_name = ? AND table_type = 'BASE TABLE'", currentSchema, table).Row().Scan(&count)
+	return count > 0, err
+}
+
+func (postgresDialect) Tables(m *Migrator, stmt *gorm.Statement) (tables []string, err error) {
+	currentSchema, _ := m.CurrentSchema(stmt, stmt.Table)
+	err = m.DB.Raw("SELECT table_name FROM information_schema.tables WHERE table_schema = ? AND table_type = 'BASE TABLE' ORDER BY table_name", currentSchema).Scan(&tables).Error
+	return
+}
+
+func (postgresDialect) HasIndex(m *Migrator, stmt *gorm.Statement, name string) (bool, error) {
+	var count int64
+	currentSchema, table := m.CurrentSchema(stmt, stmt.Table)
+	err := m.DB.Raw("SELECT count(*) FROM pg_indexes WHERE schemaname = ? AND tablename = ? AND indexname = ?", currentSchema, table, name).Row().Scan(&count)
+	return count > 0, err
+}
+
+// postgresBitSizedTypes are the types gorm sizes in bits
+var postgresBitSizedTypes = map[string]bool{"int2": true, "int4": true, "int8": true, "float4": true, "float8": true}
+
+func (postgresDialect) ColumnTypes(m *Migrator, stmt *gorm.Statement) ([]ColumnType, error) {
+	currentSchema, table := m.CurrentSchema(stmt, stmt.Table)
+	rows, err := m.DB.Raw(`SELECT c.column_name, c.udt_name, format_type(a.atttypid, a.atttypmod), c.is_nullable = 'YES',
+	c.character_maximum_length, t.typlen, c.numeric_precision, c.numeric_scale, c.datetime_precision,
+	c.column_default, col_description(a.attrelid, a.attnum), c.is_identity = 'YES',
+	EXISTS (SELECT 1 FROM pg_index i WHERE i.indrelid = a.attrelid AND i.indisprimary AND a.attnum = ANY(i.indkey)),
+	EXISTS (SELECT 1 FROM pg_constraint con WHERE con.conrelid = a.attrelid AND con.contype = 'u' AND con.conkey = ARRAY[a.attnum])
+FROM information_schema.columns c
+JOIN pg_namespace n ON n.nspname = c.table_schema
+JOIN pg_class cl ON cl.relnamespace = n.oid AND cl.relname = c.table_name
+JOIN pg_attribute a ON a.attrelid = cl.oid AND a.attname = c.column_name
+JOIN pg_type t ON t.oid = a.atttypid
+WHERE c.table_schema = ? AND c.table_name = ?
+ORDER BY c.ordinal_position`, currentSchema, table).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columnTypes []ColumnType
+	for rows.Next() {
+		var (
+			column            ColumnType
+			typeLen           sql.NullInt64
+			numericPrecision  sql.NullInt64
+			datetimePrecision sql.NullInt64
+			isIdentity        bool
+		)
+		if err := rows.Scan(
+			&column.NameValue, &column.DataTypeValue, &column.ColumnTypeValue, &column.NullableValue,
+			&column.LengthValue, &typeLen, &numericPrecision, &column.ScaleValue, &datetimePrecision,
+			&column.DefaultValueValue, &column.CommentValue, &isIdentity,
+			&column.PrimaryKeyValue, &column.UniqueValue,
+		); err != nil {
+			return nil, err
+		}
+
+		// integers and floats report their size in bits, the way gorm sizes them, other fixed size types like
+		// timestamps are sized by their precision
+		if !column.LengthValue.Valid && typeLen.Valid && typeLen.Int64 > 0 && postgresBitSizedTypes[column.DataTypeValue.String] {
+			column.LengthValue = sql.NullInt64{Int64: 8 * typeLen.Int64, Valid: true}
+		}
+
+		switch column.DataTypeValue.String {
+		case "numeric":
+			column.DecimalSizeValue = numericPrecision
+		default:
+			column.DecimalSizeValue = datetimePrecision
+			column.ScaleValue = sql.NullInt64{}
+		}
+
+		column.AutoIncrementValue = sql.NullBool{Bool: isIdentity, Valid: true}
+		if def := column.DefaultValueValue.String; strings.HasPrefix(def, "nextval(") {
+			column.AutoIncrementValue.Bool = true
+			column.DefaultValueValue = sql.NullString{}
+		} else if column.DefaultValueValue.Valid {
+			column.DefaultValueValue.String = unquoteDefault(regPostgresCast.ReplaceAllString(def, "$1"))
+		}
+
+		// comments are always known, a missing one is empty
+		column.CommentValue.Valid = true
+		column.ScanTypeValue = scanTypeOf(column.DataTypeValue.String, column.NullableValue.Bool)
+		columnTypes = append(columnTypes, column)
+	}
+
+	return columnTypes, rows.Err()
+}
+
+func (postgresDialect) Indexes(m *Migrator, stmt *gorm.Statement) ([]Index, error) {
+	currentSchema, table := m.CurrentSchema(stmt, stmt.Table)
+	rows, err := m.DB.Raw(`SELECT ic.relname, i.indisunique, i.indisprimary, con.oid IS NOT NULL, pg_get_indexdef(i.indexrelid),
+	am.amname, COALESCE(pg_get_expr(i.indpred, i.indrelid, true), ''),
+	i.indkey[k.n - 1] = 0, pg_get_indexdef(i.indexrelid, k.n::int, true), i.indoption[k.n - 1] & 1 = 1
+FROM pg_index i
+JOIN pg_class ic ON ic.oid = i.indexrelid
+JOIN pg_am am ON am.oid = ic.relam
+JOIN pg_class cl ON cl.oid = i.indrelid
+JOIN pg_namespace n ON n.oid = cl.relnamespace
+LEFT JOIN pg_constraint con ON con.conindid = i.indexrelid AND con.conrelid = i.indrelid AND con.contype IN ('p', 'u', 'x')
+CROSS JOIN LATERAL generate_series(1, i.indnkeyatts) AS k(n)
+WHERE n.nspname = ? AND cl.relname = ?
+ORDER BY ic.relname, k.n`, currentSchema, table).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var indexes []Index
+	for rows.Next() {
+		var (
+			idx          = Index{Table: stmt.Table}
+			isExpression bool
+			key          string
+			descending   bool
+		)
+		if err := rows.Scan(&idx.Name, &idx.Unique, &idx.Primary, &idx.Constraint, &idx.Definition, &idx.Method, &idx.Where,
+			&isExpression, &key, &descending); err != nil {
+			return nil, err
+		}
+		if len(indexes) == 0 || indexes[len(indexes)-1].Name != idx.Name {
+			indexes = append(indexes, idx)
+		}
+		last := &indexes[len(indexes)-1]
+		if !isExpression {
+			last.Columns = append(last.Columns, strings.Trim(key, `"`))
+		}
+		if descending {
+			key += " DESC"
+		}
+		last.Keys = append(last.Keys, key)
+	}
+
+	return indexes, rows.Err()
+}
+
+// postgresReferentialActions maps pg_constraint action codes to their SQL
+var postgresReferentialActions = map[string]string{
+	"a": "NO ACTION",
+	"r": "RESTRICT",
+	"c": "CASCADE",
+	"n": "SET NULL",
+	"d": "SET DEFAULT",
+}
+
+func (postgresDialect) ForeignKeys(m *Migrator, stmt *gorm.Statement) ([]ForeignKey, error) {
+	currentSchema, table := m.CurrentSchema(stmt, stmt.Table)
+	rows, err := m.DB.Raw(`SELECT con.conname, a.attname, CASE WHEN fn.oid = n.oid THEN fcl.relname ELSE fn.nspname || '.' || fcl.relname END,
+	fa.attname, con.confdeltype, con.confupdtype
+FROM pg_constraint con
+JOIN pg_class cl ON cl.oid = con.conrelid
+JOIN pg_namespace n ON n.oid = cl.relnamespace
+JOIN pg_class fcl ON fcl.oid = con.confrelid
+JOIN pg_namespace fn ON fn.oid = fcl.relnamespace
+CROSS JOIN LATERAL unnest(con.conkey, con.confkey) WITH ORDINALITY AS k(attnum, fattnum, ord)
+JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+JOIN pg_attribute fa ON fa.attrelid = con.confrelid AND fa.attnum = k.fattnum
+WHERE con.contype = 'f' AND n.nspname = ? AND cl.relname = ?
+ORDER BY con.conname, k.ord`, currentSchema, table).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foreignKeys []ForeignKey
+	for rows.Next() {
+		var (
+			fk                       = ForeignKey{Table: stmt.Table}
+			column, referencedColumn string
+		)
+		if err := rows.Scan(&fk.Name, &column, &fk.ReferencedTable, &referencedColumn, &fk.OnDelete, &fk.OnUpdate); err != nil {
+			return nil, err
+		}
+		fk.OnDelete = postgresReferentialActions[fk.OnDelete]
+		fk.OnUpdate = postgresReferentialActions[fk.OnUpdate]
+		if len(foreignKeys) == 0 || foreignKeys[len(foreignKeys)-1].Name != fk.Name {
+			foreignKeys = append(foreignKeys, fk)
+		}
+		last := &foreignKeys[len(foreignKeys)-1]
+		last.Columns = append(last.Columns, column)
+		last.ReferencedColumns = append(last.ReferencedColumns, referencedColumn)
+	}
+
+	return foreignKeys, rows.Err()
+}
+
+func (postgresDialect) Checks(m *Migrator, stmt *gorm.Statement) ([]Check, error) {
+	currentSchema, table := m.CurrentSchema(stmt, stmt.Table)
+	rows, err := m.DB.Raw(`SELECT con.conname, pg_get_expr(con.conbin, con.conrelid), COALESCE(a.attname, '')
+FROM pg_constraint con
+JOIN pg_class cl ON cl.oid = con.conrelid
+JOIN pg_namespace n ON n.oid = cl.relnamespace
+LEFT JOIN LATERAL unnest(con.conkey) AS k(attnum) ON true
+LEFT JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+WHERE con.contype = 'c' AND n.nspname = ? AND cl.relname = ?
+ORDER BY con.conname`, currentSchema, table).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checks []Check
+	for rows.Next() {
+		var (
+			chk    = Check{Table: stmt.Table}
+			column string
+		)
+		if err := rows.Scan(&chk.Name, &chk.Expression, &column); err != nil {
+			return nil, err
+		}
+		if len(checks) == 0 || checks[len(checks)-1].Name != chk.Name {
+			checks = append(checks, chk)
+		}
+		if column != "" {
+			last := &checks[len(checks)-1]
+			last.Columns = append(last.Columns, column)
+		}
+	}
+
+	return checks, rows.Err()
+}
+
+// postgresSerialTypes maps integer types to the serial type that recreates their sequence default
+var postgresSerialTypes = map[string]string{
+	"int2": "smallserial",
+	"int4": "serial",
+	"int8": "bigserial",
+}
+
+func (postgresDialect) ColumnDefinition(m *Migrator, column ColumnType) string {
+	dataType, _ := column.ColumnType()
+	if autoIncrement, _ := column.AutoIncrement(); autoIncrement {
+		if serial, ok := postgresSerialTypes[column.DatabaseTypeName()]; ok {
+			dataType = serial
+		}
+	}
+	if nullable, ok := column.Nullable(); ok && !nullable {
+		dataType += " NOT NULL"
+	}
+	if value, ok := defaultLiteral(column); ok {
+		dataType += " DEFAULT " + value
+	}
+	if unique, _ := column.Unique(); unique {
+		dataType += " UNIQUE"
+	}
+	return dataType
+}
+
+func (postgresDialect) ColumnCommentSQL(m *Migrator, stmt *gorm.Statement, column ColumnType) string {
+	if comment, ok := column.Comment(); ok && comment != "" {
+		return buildRawSQL(m.DB, "COMMENT ON COLUMN ?.? IS ?", m.CurrentTable(stmt), clause.Column{Name: column.Name()}, clause.Expr{SQL: quoteString(comment)})
+	}
+	return ""
+}
+
+func (d postgresDialect) AddColumnSQL(m *Migrator, stmt *gorm.Statement, column ColumnType) string {
+	return addColumnSQL(m, stmt, d, column)
+}
+
+func (postgresDialect) DropColumnSQL(m *Migrator, stmt *gorm.Statement, name string) string {
+	return dropColumnSQL(m, stmt, name)
+}
+
+func (postgresDialect) RenameTableSQL(m *Migrator, stmt *gorm.Statement, name string) string {
+	return renameTableSQL(m, stmt, unqualifiedTable(name))
+}
+
+func (postgresDialect) RenameColumnSQL(m *Migrator, stmt *gorm.Statement, column ColumnType, name string) string {
+	return renameColumnSQL(m, stmt, column.Name(), name)
+}
+
+func (postgresDialect) AlterColumnSQL(m *Migrator, stmt *gorm.Statement, column ColumnType, changes ColumnChanges) string {
+	var sql string
+	name := clause.Column{Name: column.Name()}
+	if changes.Type {
+		dataType, _ := column.ColumnType()
+		// serial only exists in CREATE TABLE, the sequence default outlives a type change
+		for integer, serial := range postgresSerialTypes {
+			if strings.EqualFold(dataType, serial) {
+				dataType = integer
+			}
+		}
+		sql += buildRawSQL(m.DB, "ALTER TABLE ? ALTER COLUMN ? TYPE ? USING ?::?", m.CurrentTable(stmt), name, clause.Expr{SQL: dataType}, name, clause.Expr{SQL: dataType})
+	}
+	if changes.Nullable {
+		if nullable, _ := column.Nullable(); nullable {
+			sql += buildRawSQL(m.DB, "ALTER TABLE ? ALTER COLUMN ? DROP NOT NULL", m.CurrentTable(stmt), name)
+		} else {
+			sql += buildRawSQL(m.DB, "ALTER TABLE ? ALTER COLUMN ? SET NOT NULL", m.CurrentTable(stmt), name)
+		}
+	}
+	if changes.Default {
+		if value, ok := defaultLiteral(column); ok {
+			sql += buildRawSQL(m.DB, "ALTER TABLE ? ALTER COLUMN ? SET DEFAULT ?", m.CurrentTable(stmt), name, clause.Expr{SQL: value})
+		} else {
+			sql += buildRawSQL(m.DB, "ALTER TABLE ? ALTER COLUMN ? DROP DEFAULT", m.CurrentTable(stmt), name)
+		}
+	}
+	if changes.Unique {
+		// inline UNIQUE constraints are named <table>_<column>_key
+		constraint := clause.Column{Name: stmt.Table + "_" + column.Name() + "_key"}
+		if unique, _ := column.Unique(); unique {
+			sql += buildRawSQL(m.DB, "ALTER TABLE ? ADD CONSTRAINT ? UNIQUE (?)", m.CurrentTable(stmt), constraint, name)
+		} else {
+			sql += buildRawSQL(m.DB, "ALTER TABLE ? DROP CONSTRAINT ?", m.CurrentTable(stmt), constraint)
+		}
+	}
+	if changes.Comment {
+		comment, _ := column.Comment()
+		value := "NULL"
+		if comment != "" {
+			value = quoteString(comment)
+		}
+		sql += buildRawSQL(m.DB, "COMMENT ON COLUMN ?.? IS ?", m.CurrentTable(stmt), name, clause.Expr{SQL: value})
+	}
+	return sql
+}
+
+// currentSchemaName returns the schema stmt's table was explicitly qualified with
+func currentSchemaName(m *Migrator, stmt *gorm.Statement) (string, bool) {
+	currentSchema, _ := m.CurrentSchema(stmt, stmt.Table)
+	schemaName, ok := currentSchema.(string)
+	return schemaName, ok
+}
+
+func (postgresDialect) CreateIndexSQL(m *Migrator, stmt *gorm.Statement, idx *schema.Index) string {
+	sql := createIndexSQL(m, stmt, idx, true)
+	if idx.Comment != "" {
+		name := clause.Table{Name: idx.Name}
+		if schemaName, ok := currentSchemaName(m, stmt); ok {
+			name.Name = schemaName + "." + idx.Name
+		}
+		sql += buildRawSQL(m.DB, "COMMENT ON INDEX ? IS ?", name, clause.Expr{SQL: quoteString(idx.Comment)})
+	}
+	return sql
+}
+
+func (postgresDialect) DropIndexSQL(m *Migrator, stmt *gorm.Statement, idx Index) string {
+	// indexes live in their table's schema
+	if schemaName, ok := currentSchemaName(m, stmt); ok {
+		return buildRawSQL(m.DB, "DROP INDEX ?", clause.Table{Name: schemaName + "." + idx.Name})
+	}
+	return buildRawSQL(m.DB, "DROP INDEX ?", clause.Column{Name: idx.Name})
+}
+
+func (postgresDialect) AddForeignKeySQL(m *Migrator, stmt *gorm.Statement, fk ForeignKey) string {
+	return addForeignKeySQL(m, stmt, fk)
+}
+
+func (postgresDialect) DropForeignKeySQL(m *Migrator, stmt *gorm.Statement, fk ForeignKey) string {
+	return buildRawSQL(m.DB, "ALTER TABLE ? DROP CONSTRAINT ?", m.CurrentTable(stmt), clause.Column{Name: fk.Name})
+}
+
+func (postgresDialect) AddCheckSQL(m *Migrator, stmt *gorm.Statement, chk Check) string {
+	return addCheckSQL(m, stmt, chk)
+}
+
+func (postgresDialect) DropCheckSQL(m *Migrator, stmt *gorm.Statement, chk Check) string {
+	return buildRawSQL(m.DB, "ALTER TABLE ? DROP CONSTRAINT ?", m.CurrentTable(stmt), clause.Column{Name: chk.Name})
+}
+
+// ResetSchema drops the current schema along with the types, functions and sequences in it, and creates it
+// again empty
+func (postgresDialect) ResetSchema(m *Migrator) error {
+	var schemaName string
+	if err := m.DB.Raw("SELECT current_schema()").Row().Scan(&schemaName); err != nil {
+		return err
+	}
+	if err := m.DB.Exec("DROP SCHEMA ? CASCADE", clause.Table{Name: schemaName}).Error; err != nil {
+		return err
+	}
+	return m.DB.Exec("CREATE SCHEMA ?", clause.Table{Name: schemaName}).Error
+}
+
+func (postgresDialect) BeginSQL() string {
+	return "BEGIN;"
+}
+
+func (postgresDialect) MigrateDriver(db *sql.DB) (database.Driver, error) {
+	return postgres.WithInstance(db, &postgres.Config{})
+}
+
+// postgresTypeAliases maps type names to the names pg_type knows them by
+var postgresTypeAliases = map[string]string{
+	"boolean":                     "bool",
+	"smallint":                    "int2",
+	"smallserial":                 "int2",
+	"serial2":                     "int2",
+	"integer":                     "int4",
+	"int":                         "int4",
+	"serial":                      "int4",
+	"serial4":                     "int4",
+	"bigint":                      "int8",
+	"bigserial":                   "int8",
+	"serial8":                     "int8",
+	"real":                        "float4",
+	"float":                       "float8",
+	"double precision":            "float8",
+	"decimal":                     "numeric",
+	"character varying":           "varchar",
+	"character":                   "bpchar",
+	"char":                        "bpchar",
+	"timestamp with time zone":    "timestamptz",
+	"timestamp without time zone": "timestamp",
+	"time with time zone":         "timetz",
+	"time without time zone":      "time",
+}
+
+func (d postgresDialect) NormalizeType(columnType string) string {
+	// array types are named after their element type with a leading underscore
+	if base := baseType(columnType); strings.HasSuffix(base, "[]") {
+		return "_" + d.NormalizeType(strings.TrimSuffix(base, "[]"))
+	}
+	return normalizeAlias(columnType, postgresTypeAliases)
+}
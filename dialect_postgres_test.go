@@ -0,0 +1,48 @@
+//go:build integration
+
+package migrator
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+)
+
+// openPostgres opens the database MIGRATOR_TEST_POSTGRES_DSN points at, like one started with
+// docker run -e POSTGRES_PASSWORD=test -p 5432:5432 postgres
+func openPostgres(t *testing.T) *Migrator {
+	db := openIntegration(t, "MIGRATOR_TEST_POSTGRES_DSN", postgres.Open)
+	return testMigrator(t, db)
+}
+
+func TestPostgresDialect(t *testing.T) {
+	m := openPostgres(t)
+	var database string
+	if err := m.DB.Raw("SELECT current_database()").Row().Scan(&database); err != nil {
+		t.Fatal(err)
+	}
+	testDialectIntrospection(t, m.DB, database)
+}
+
+func TestPostgresAlterColumn(t *testing.T) {
+	testAlterColumnRoundTrip(t, openPostgres(t), &alterItem{}, &alterItemChanged{})
+}
+
+func TestPostgresAlterSerialColumn(t *testing.T) {
+	testAlterColumnRoundTrip(t, openPostgres(t), &alterCounter{}, &alterCounterWide{})
+}
+
+type postgresReading struct {
+	ID      uint
+	TakenAt time.Time `gorm:"precision:3"`
+}
+
+func (postgresReading) TableName() string { return "postgres_readings" }
+
+func TestPostgresTimePrecision(t *testing.T) {
+	// the precision of a timestamp is not a size in bits, it is not taken for one
+	m := openPostgres(t)
+	migrateTo(t, m, &postgresReading{})
+	assertNoDrift(t, m)
+}
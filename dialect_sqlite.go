@@ -0,0 +1,816 @@
+package migrator
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+type sqliteDialect struct{}
+
+// schemaAndTable splits a `schema.table` name, defaulting to the main database
+func (sqliteDialect) schemaAndTable(stmt *gorm.Statement) (string, string) {
+	if tables := strings.Split(stmt.Table, "."); len(tables) == 2 {
+		return tables[0], tables[1]
+	}
+	return "main", stmt.Table
+}
+
+// master returns the sqlite_master table of schemaName
+func (sqliteDialect) master(schemaName string) clause.Table {
+	return clause.Table{Name: schemaName + ".sqlite_master"}
+}
+
+func (sqliteDialect) CurrentDatabase(m *Migrator) (string, error) {
+	return "main", nil
+}
+
+func (d sqliteDialect) HasTable(m *Migrator, stmt *gorm.Statement) (bool, error) {
+	var count int64
+	schemaName, table := d.schemaAndTable(stmt)
+	err := m.DB.Raw("SELECT count(*) FROM ? WHERE type = 'table' AND name = ?", d.master(schemaName), table).Row().Scan(&count)
+	return count > 0, err
+}
+
+func (d sqliteDialect) Tables(m *Migrator, stmt *gorm.Statement) (tables []string, err error) {
+	schemaName, _ := d.schemaAndTable(stmt)
+	err = m.DB.Raw("SELECT name FROM ? WHERE type = 'table' AND name NOT LIKE 'sqlite\\_%' ESCAPE '\\' ORDER BY name", d.master(schemaName)).Scan(&tables).Error
+	return
+}
+
+func (d sqliteDialect) HasIndex(m *Migrator, stmt *gorm.Statement, name string) (bool, error) {
+	var count int64
+	schemaName, table := d.schemaAndTable(stmt)
+	err := m.DB.Raw("SELECT count(*) FROM ? WHERE type = 'index' AND tbl_name = ? AND name = ?", d.master(schemaName), table, name).Row().Scan(&count)
+	return count > 0, err
+}
+
+func (d sqliteDialect) ColumnTypes(m *Migrator, stmt *gorm.Statement) ([]ColumnType, error) {
+	schemaName, table := d.schemaAndTable(stmt)
+	rows, err := m.DB.Raw("SELECT name, type, \"notnull\", dflt_value, pk FROM pragma_table_info(?, ?) ORDER BY cid", table, schemaName).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		columnTypes []ColumnType
+		primaryKeys int
+	)
+	for rows.Next() {
+		var (
+			column  ColumnType
+			notNull bool
+			pk      int
+		)
+		if err := rows.Scan(&column.NameValue, &column.ColumnTypeValue, &notNull, &column.DefaultValueValue, &pk); err != nil {
+			return nil, err
+		}
+
+		declared := strings.ToLower(column.ColumnTypeValue.String)
+		dataType := declared
+		if i := strings.Index(dataType, "("); i >= 0 {
+			dataType = strings.TrimSpace(dataType[:i])
+		}
+		column.DataTypeValue = sql.NullString{String: dataType, Valid: true}
+
+		if size, scale, ok := parseTypeArgs(declared); ok {
+			switch dataType {
+			case "decimal", "numeric":
+				column.DecimalSizeValue = sql.NullInt64{Int64: size, Valid: true}
+				column.ScaleValue = sql.NullInt64{Int64: scale, Valid: true}
+			default:
+				column.LengthValue = sql.NullInt64{Int64: size, Valid: true}
+			}
+		}
+
+		if column.DefaultValueValue.Valid {
+			column.DefaultValueValue.String = unquoteDefault(column.DefaultValueValue.String)
+		}
+
+		if pk > 0 {
+			primaryKeys++
+		}
+		column.PrimaryKeyValue = sql.NullBool{Bool: pk > 0, Valid: true}
+		column.NullableValue = sql.NullBool{Bool: !notNull, Valid: true}
+		column.UniqueValue = sql.NullBool{Valid: true}
+		column.AutoIncrementValue = sql.NullBool{Valid: true}
+		column.ScanTypeValue = scanTypeOf(dataType, column.NullableValue.Bool)
+		columnTypes = append(columnTypes, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// a lone INTEGER PRIMARY KEY aliases the rowid and is assigned automatically
+	if primaryKeys == 1 {
+		for i := range columnTypes {
+			if columnTypes[i].PrimaryKeyValue.Bool && columnTypes[i].DataTypeValue.String == "integer" {
+				columnTypes[i].AutoIncrementValue.Bool = true
+			}
+		}
+	}
+
+	// a `unique` column is backed by a single column index created by a UNIQUE constraint
+	indexRows, err := m.DB.Raw(`SELECT ii.name FROM pragma_index_list(?, ?) il, pragma_index_info(il.name, ?) ii
+WHERE il."unique" AND il.origin = 'u' AND (SELECT COUNT(*) FROM pragma_index_info(il.name, ?)) = 1`,
+		table, schemaName, schemaName, schemaName).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer indexRows.Close()
+
+	for indexRows.Next() {
+		var columnName string
+		if err := indexRows.Scan(&columnName); err != nil {
+			return nil, err
+		}
+		for i := range columnTypes {
+			if columnTypes[i].NameValue.String == columnName {
+				columnTypes[i].UniqueValue.Bool = true
+			}
+		}
+	}
+
+	return columnTypes, indexRows.Err()
+}
+
+var (
+	regSQLiteForeignKey = regexp.MustCompile("(?i)CONSTRAINT\\s+[\"`\\[]?([^\"`\\]\\s]+)[\"`\\]]?\\s+FOREIGN\\s+KEY\\s*\\(([^)]*)\\)")
+	regSQLiteIndexOn    = regexp.MustCompile("(?is)\\bON\\s+(?:[\"`\\[]?[^\\s(]+[\"`\\]]?)\\s*\\(")
+	regSQLiteIndexWhere = regexp.MustCompile(`(?is)^\s*WHERE\s+(.*?)\s*;?\s*$`)
+	regSQLiteCheck      = regexp.MustCompile("(?i)CONSTRAINT\\s+[\"`\\[]?([^\"`\\]\\s]+)[\"`\\]]?\\s+CHECK\\s*\\(")
+)
+
+// tableSQL returns the CREATE TABLE statement SQLite recorded for stmt's table
+func (d sqliteDialect) tableSQL(m *Migrator, stmt *gorm.Statement) (string, error) {
+	var createSQL sql.NullString
+	schemaName, table := d.schemaAndTable(stmt)
+	err := m.DB.Raw("SELECT sql FROM ? WHERE type = 'table' AND name = ?", d.master(schemaName), table).Row().Scan(&createSQL)
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	return createSQL.String, err
+}
+
+func (d sqliteDialect) Indexes(m *Migrator, stmt *gorm.Statement) ([]Index, error) {
+	schemaName, table := d.schemaAndTable(stmt)
+	rows, err := m.DB.Raw(`SELECT il.name, il."unique", il.origin, COALESCE(sm.sql, ''), ii.name
+FROM pragma_index_list(?, ?) il
+JOIN pragma_index_info(il.name, ?) ii
+LEFT JOIN ? sm ON sm.type = 'index' AND sm.name = il.name
+ORDER BY il.name, ii.seqno`, table, schemaName, schemaName, d.master(schemaName)).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var indexes []Index
+	for rows.Next() {
+		var (
+			idx    = Index{Table: stmt.Table}
+			origin string
+			column sql.NullString
+		)
+		if err := rows.Scan(&idx.Name, &idx.Unique, &origin, &idx.Definition, &column); err != nil {
+			return nil, err
+		}
+		idx.Primary = origin == "pk"
+		idx.Constraint = origin != "c"
+		if len(indexes) == 0 || indexes[len(indexes)-1].Name != idx.Name {
+			indexes = append(indexes, idx)
+		}
+		// expression key parts have no column name
+		if column.Valid {
+			last := &indexes[len(indexes)-1]
+			last.Columns = append(last.Columns, column.String)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range indexes {
+		indexes[i].Keys, indexes[i].Where = parseSQLiteIndex(indexes[i].Definition)
+	}
+	return indexes, nil
+}
+
+// parseSQLiteIndex reads the key parts and predicate out of the CREATE INDEX statement SQLite recorded
+func parseSQLiteIndex(definition string) (keys []string, where string) {
+	loc := regSQLiteIndexOn.FindStringIndex(definition)
+	if loc == nil {
+		return nil, ""
+	}
+
+	start, depth := loc[1], 1
+	for i := start; i < len(definition); i++ {
+		switch definition[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 1 {
+				keys = append(keys, strings.TrimSpace(definition[start:i]))
+				start = i + 1
+			}
+		}
+		if depth == 0 {
+			keys = append(keys, strings.TrimSpace(definition[start:i]))
+			if matches := regSQLiteIndexWhere.FindStringSubmatch(definition[i+1:]); matches != nil {
+				where = strings.TrimSpace(matches[1])
+			}
+			return keys, where
+		}
+	}
+	return nil, ""
+}
+
+func (d sqliteDialect) ForeignKeys(m *Migrator, stmt *gorm.Statement) ([]ForeignKey, error) {
+	createSQL, err := d.tableSQL(m, stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite does not expose constraint names, recover them from the CREATE TABLE statement
+	names := map[string]string{}
+	for _, match := range regSQLiteForeignKey.FindAllStringSubmatch(createSQL, -1) {
+		var columns []string
+		for _, column := range strings.Split(match[2], ",") {
+			columns = append(columns, strings.Trim(strings.TrimSpace(column), "\"`[]"))
+		}
+		names[strings.Join(columns, ",")] = match[1]
+	}
+
+	schemaName, table := d.schemaAndTable(stmt)
+	rows, err := m.DB.Raw(`SELECT id, "table", "from", "to", on_delete, on_update FROM pragma_foreign_key_list(?, ?) ORDER BY id, seq`, table, schemaName).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		foreignKeys []ForeignKey
+		ids         []int
+	)
+	for rows.Next() {
+		var (
+			id                       int
+			fk                       = ForeignKey{Table: stmt.Table}
+			column, referencedColumn string
+		)
+		if err := rows.Scan(&id, &fk.ReferencedTable, &column, &referencedColumn, &fk.OnDelete, &fk.OnUpdate); err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 || ids[len(ids)-1] != id {
+			ids = append(ids, id)
+			foreignKeys = append(foreignKeys, fk)
+		}
+		last := &foreignKeys[len(foreignKeys)-1]
+		last.Columns = append(last.Columns, column)
+		last.ReferencedColumns = append(last.ReferencedColumns, referencedColumn)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range foreignKeys {
+		foreignKeys[i].Name = names[strings.Join(foreignKeys[i].Columns, ",")]
+	}
+	return foreignKeys, nil
+}
+
+func (d sqliteDialect) Checks(m *Migrator, stmt *gorm.Statement) ([]Check, error) {
+	createSQL, err := d.tableSQL(m, stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	var checks []Check
+	for _, loc := range regSQLiteCheck.FindAllStringSubmatchIndex(createSQL, -1) {
+		// the expression runs from the opening parenthesis the match ends on to its balancing one
+		depth, start := 1, loc[1]
+		end := start
+		for ; end < len(createSQL) && depth > 0; end++ {
+			switch createSQL[end] {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+		}
+		if depth != 0 {
+			continue
+		}
+		checks = append(checks, Check{
+			Name:       createSQL[loc[2]:loc[3]],
+			Table:      stmt.Table,
+			Expression: strings.TrimSpace(createSQL[start : end-1]),
+		})
+	}
+	return checks, nil
+}
+
+// sqliteUnsupported renders a comment in place of a statement SQLite's ALTER TABLE cannot express
+func sqliteUnsupported(format string, args ...interface{}) string {
+	return "-- SQLite cannot " + fmt.Sprintf(format, args...) + " without rebuilding the table \n"
+}
+
+func (sqliteDialect) ColumnDefinition(m *Migrator, column ColumnType) string {
+	dataType, _ := column.ColumnType()
+	if nullable, ok := column.Nullable(); ok && !nullable {
+		dataType += " NOT NULL"
+	}
+	if value, ok := defaultLiteral(column); ok {
+		dataType += " DEFAULT " + value
+	}
+	if unique, _ := column.Unique(); unique {
+		dataType += " UNIQUE"
+	}
+	return dataType
+}
+
+func (sqliteDialect) ColumnCommentSQL(m *Migrator, stmt *gorm.Statement, column ColumnType) string {
+	return ""
+}
+
+func (d sqliteDialect) AddColumnSQL(m *Migrator, stmt *gorm.Statement, column ColumnType) string {
+	// ADD COLUMN cannot declare a UNIQUE column
+	unique := column.UniqueValue
+	column.UniqueValue.Bool = false
+
+	sql := addColumnSQL(m, stmt, d, column)
+	if unique.Bool {
+		sql += sqliteUnsupported("add a UNIQUE constraint to column %s", column.Name())
+	}
+	return sql
+}
+
+func (sqliteDialect) DropColumnSQL(m *Migrator, stmt *gorm.Statement, name string) string {
+	return dropColumnSQL(m, stmt, name)
+}
+
+func (sqliteDialect) RenameTableSQL(m *Migrator, stmt *gorm.Statement, name string) string {
+	return renameTableSQL(m, stmt, unqualifiedTable(name))
+}
+
+func (sqliteDialect) RenameColumnSQL(m *Migrator, stmt *gorm.Statement, column ColumnType, name string) string {
+	return renameColumnSQL(m, stmt, column.Name(), name)
+}
+
+func (sqliteDialect) AlterColumnSQL(m *Migrator, stmt *gorm.Statement, column ColumnType, changes ColumnChanges) string {
+	return sqliteUnsupported("change the %s of column %s in table %s", changes, column.Name(), stmt.Table)
+}
+
+func (sqliteDialect) CreateIndexSQL(m *Migrator, stmt *gorm.Statement, idx *schema.Index) string {
+	// SQLite has a single kind of index and nowhere to keep a comment
+	plain := *idx
+	plain.Type, plain.Comment = "", ""
+	return createIndexSQL(m, stmt, &plain, false)
+}
+
+func (d sqliteDialect) DropIndexSQL(m *Migrator, stmt *gorm.Statement, idx Index) string {
+	if schemaName, _ := d.schemaAndTable(stmt); schemaName != "main" {
+		return buildRawSQL(m.DB, "DROP INDEX ?", clause.Table{Name: schemaName + "." + idx.Name})
+	}
+	return buildRawSQL(m.DB, "DROP INDEX ?", clause.Column{Name: idx.Name})
+}
+
+func (sqliteDialect) AddForeignKeySQL(m *Migrator, stmt *gorm.Statement, fk ForeignKey) string {
+	return sqliteUnsupported("add foreign key %s to table %s", fk.Name, stmt.Table)
+}
+
+func (sqliteDialect) DropForeignKeySQL(m *Migrator, stmt *gorm.Statement, fk ForeignKey) string {
+	return sqliteUnsupported("drop foreign key %s from table %s", fk.Name, stmt.Table)
+}
+
+func (sqliteDialect) AddCheckSQL(m *Migrator, stmt *gorm.Statement, chk Check) string {
+	return sqliteUnsupported("add check constraint %s to table %s", chk.Name, stmt.Table)
+}
+
+func (sqliteDialect) DropCheckSQL(m *Migrator, stmt *gorm.Statement, chk Check) string {
+	return sqliteUnsupported("drop check constraint %s from table %s", chk.Name, stmt.Table)
+}
+
+// sqliteDropColumnVersion is the first SQLite release with ALTER TABLE ... DROP COLUMN
+const sqliteDropColumnVersion = "3.35.0"
+
+// RebuiltTables returns the tables of the operations ALTER TABLE cannot apply: changing a column or a
+// constraint, adding a column it cannot declare, and dropping a key column or, before 3.35, any column
+func (d sqliteDialect) RebuiltTables(m *Migrator, ops []Operation) (map[string]bool, error) {
+	version, err := d.version(m)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := map[string]bool{}
+	for _, op := range ops {
+		switch op := op.(type) {
+		case *AlterColumn, *AddForeignKey, *DropForeignKey, *AddCheck, *DropCheck:
+			tables[tableOf(op)] = true
+		case *AddColumn:
+			if op.Field.PrimaryKey || op.Field.Unique || (op.Field.NotNull && fieldDefault(op.Field) == "") {
+				tables[op.Table] = true
+			}
+		case *DropColumn:
+			primaryKey, _ := op.Column.PrimaryKey()
+			unique, _ := op.Column.Unique()
+			if primaryKey || unique || compareVersions(version, sqliteDropColumnVersion) < 0 {
+				tables[op.Table] = true
+			}
+		}
+	}
+	// a renamed table is rebuilt under its previous name, along with the operations on it under either
+	for _, op := range ops {
+		if op, ok := op.(*RenameTable); ok && (tables[op.Table] || tables[op.From]) {
+			tables[op.Table], tables[op.From] = true, true
+		}
+	}
+	return tables, nil
+}
+
+// version returns the release of the database, or offline the configured SQLiteVersion
+func (sqliteDialect) version(m *Migrator) (string, error) {
+	if m.Offline {
+		if m.SQLiteVersion == "" {
+			return sqliteDropColumnVersion, nil
+		}
+		return m.SQLiteVersion, nil
+	}
+	var version string
+	if err := m.DB.Raw("SELECT sqlite_version()").Row().Scan(&version); err != nil {
+		return "", fmt.Errorf("error reading the SQLite version: %w", err)
+	}
+	return version, nil
+}
+
+// compareVersions orders two dotted version numbers
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			fmt.Sscan(as[i], &x)
+		}
+		if i < len(bs) {
+			fmt.Sscan(bs[i], &y)
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// sqliteTable is a table as the catalog describes it, the shape a rebuild goes from and to
+type sqliteTable struct {
+	columns    []ColumnType
+	dependents *tableDependents
+	// renamed maps the columns renamed on the way to this shape to their previous names
+	renamed map[string]string
+}
+
+// RebuildTableSQL follows SQLite's procedure for schema changes ALTER TABLE cannot make: create the new
+// table under a temporary name, copy the rows over, drop the old table, rename the new one into its place,
+// then recreate the indexes, triggers and views that went with the old one. The down migration rebuilds
+// the table the catalog describes. A renamed table is rebuilt under its previous name, then renamed.
+func (d sqliteDialect) RebuildTableSQL(m *Migrator, table string, ops []Operation) (up string, down string, err error) {
+	var rename *RenameTable
+	source := table
+	for _, op := range ops {
+		if op, ok := op.(*RenameTable); ok {
+			rename, source = op, op.From
+		}
+	}
+
+	err = m.RunWithValue(source, func(stmt *gorm.Statement) error {
+		// the catalog is read through the migrator's dialect, which describes the table from a snapshot offline
+		before := sqliteTable{}
+		if before.dependents, err = m.loadTableDependents(stmt); err != nil {
+			return err
+		}
+		if before.columns, err = before.dependents.dialect.ColumnTypes(m, stmt); err != nil {
+			return err
+		}
+		// triggers and views are not part of the snapshot, offline there are none to recreate
+		var triggers, views []sqliteObject
+		if !m.Offline {
+			if triggers, err = d.schemaObjects(m, stmt, "trigger"); err != nil {
+				return err
+			}
+			if views, err = d.schemaObjects(m, stmt, "view"); err != nil {
+				return err
+			}
+		}
+
+		after, err := d.applyOperations(m, stmt, before, ops)
+		if err != nil {
+			return err
+		}
+		restored := map[string]string{}
+		for name, previous := range after.renamed {
+			restored[previous] = name
+		}
+		up = d.rebuildSQL(m, stmt, before, after, after.renamed, triggers, views)
+		down = d.rebuildSQL(m, stmt, after, before, restored, triggers, views)
+		return nil
+	})
+	if err != nil || rename == nil {
+		return up, down, err
+	}
+	renameUp, renameDown, err := m.renderOperation(d, rename)
+	return up + renameUp, renameDown + down, err
+}
+
+func (sqliteDialect) ForeignKeysSQL(enabled bool) string {
+	if enabled {
+		return "PRAGMA foreign_keys = ON;"
+	}
+	return "PRAGMA foreign_keys = OFF;"
+}
+
+// sqliteObject is a trigger or view recorded in sqlite_master
+type sqliteObject struct {
+	name, sql string
+}
+
+// schemaObjects returns the triggers on stmt's table, or the views that may select from it, which dropping
+// the table drops or breaks
+func (d sqliteDialect) schemaObjects(m *Migrator, stmt *gorm.Statement, kind string) ([]sqliteObject, error) {
+	schemaName, table := d.schemaAndTable(stmt)
+	query := "SELECT name, sql FROM ? WHERE type = ? AND tbl_name = ? AND sql IS NOT NULL ORDER BY name"
+	if kind == "view" {
+		// a view that only mentions the table's name is recreated unchanged
+		query = "SELECT name, sql FROM ? WHERE type = ? AND instr(lower(sql), lower(?)) > 0 ORDER BY name"
+	}
+	rows, err := m.DB.Raw(query, d.master(schemaName), kind, table).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var objects []sqliteObject
+	for rows.Next() {
+		var object sqliteObject
+		if err := rows.Scan(&object.name, &object.sql); err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+	return objects, rows.Err()
+}
+
+// applyOperations returns the table of stmt that results from applying ops to it
+func (d sqliteDialect) applyOperations(m *Migrator, stmt *gorm.Statement, table sqliteTable, ops []Operation) (sqliteTable, error) {
+	result := sqliteTable{
+		columns: append([]ColumnType(nil), table.columns...),
+		dependents: &tableDependents{
+			dialect:     d,
+			indexes:     append([]Index(nil), table.dependents.indexes...),
+			foreignKeys: append([]ForeignKey(nil), table.dependents.foreignKeys...),
+			checks:      append([]Check(nil), table.dependents.checks...),
+		},
+		renamed: map[string]string{},
+	}
+	dependents := result.dependents
+
+	for _, op := range ops {
+		switch op := op.(type) {
+		case *AddColumn:
+			result.columns = append(result.columns, m.columnTypeOf(op.Field))
+		case *DropColumn:
+			result.removeColumn(op.Column.Name())
+		case *RenameTable:
+			// renamed once rebuilt
+		case *RenameColumn:
+			result.renameColumn(op.Column.Name(), op.Field.DBName)
+		case *AlterColumn:
+			// the column's constraint index goes with the old definition, the new one declares its own
+			for i, column := range result.columns {
+				if column.Name() == op.Before.Name() {
+					result.removeConstraintIndexes(column.Name())
+					result.columns[i] = m.columnTypeOf(op.After)
+				}
+			}
+		case *CreateIndex:
+			var idx Index
+			if err := m.RunWithValue(op.Model, func(modelStmt *gorm.Statement) error {
+				// the index is created on the rebuilt table, before it is renamed
+				modelStmt.Table = stmt.Table
+				idx = Index{
+					Name:       op.Index.Name,
+					Table:      stmt.Table,
+					Unique:     strings.EqualFold(op.Index.Class, "UNIQUE"),
+					Definition: strings.TrimSuffix(d.CreateIndexSQL(m, modelStmt, op.Index), "; \n"),
+				}
+				for _, opt := range op.Index.Fields {
+					idx.Columns = append(idx.Columns, opt.DBName)
+				}
+				return nil
+			}); err != nil {
+				return result, err
+			}
+			result.removeIndex(idx.Name)
+			dependents.indexes = append(dependents.indexes, idx)
+		case *DropIndex:
+			result.removeIndex(op.Index.Name)
+		case *AddForeignKey:
+			dependents.foreignKeys = append(dependents.foreignKeys, op.ForeignKey)
+		case *DropForeignKey:
+			for i, fk := range dependents.foreignKeys {
+				if fk.Name == op.ForeignKey.Name && strings.Join(fk.Columns, ",") == strings.Join(op.ForeignKey.Columns, ",") {
+					dependents.foreignKeys = append(dependents.foreignKeys[:i:i], dependents.foreignKeys[i+1:]...)
+					break
+				}
+			}
+		case *AddCheck:
+			dependents.checks = append(dependents.checks, op.Check)
+		case *DropCheck:
+			for i, chk := range dependents.checks {
+				if chk.Name == op.Check.Name {
+					dependents.checks = append(dependents.checks[:i:i], dependents.checks[i+1:]...)
+					break
+				}
+			}
+		default:
+			return result, fmt.Errorf("cannot rebuild table %s to %s", op.Change().Table, op.Change().Action)
+		}
+	}
+	return result, nil
+}
+
+// removeColumn removes the column name along with the constraint indexes that cover it
+func (table *sqliteTable) removeColumn(name string) {
+	for i, column := range table.columns {
+		if column.Name() == name {
+			table.columns = append(table.columns[:i:i], table.columns[i+1:]...)
+			break
+		}
+	}
+	table.removeConstraintIndexes(name)
+}
+
+// renameColumn renames the column from along with it in the constraint indexes that cover it, the rows
+// are copied over from the column's original name
+func (table *sqliteTable) renameColumn(from, to string) {
+	for i, column := range table.columns {
+		if column.Name() == from {
+			table.columns[i].NameValue = sql.NullString{String: to, Valid: true}
+		}
+	}
+	for i, idx := range table.dependents.indexes {
+		if !idx.Constraint {
+			continue
+		}
+		columns := append([]string(nil), idx.Columns...)
+		for j := range columns {
+			if columns[j] == from {
+				columns[j] = to
+			}
+		}
+		table.dependents.indexes[i].Columns = columns
+	}
+	original := from
+	if previous, ok := table.renamed[from]; ok {
+		delete(table.renamed, from)
+		original = previous
+	}
+	table.renamed[to] = original
+}
+
+// removeConstraintIndexes removes the indexes UNIQUE constraints on column name created
+func (table *sqliteTable) removeConstraintIndexes(name string) {
+	indexes := table.dependents.indexes[:0:0]
+	for _, idx := range table.dependents.indexes {
+		covers := false
+		for _, column := range idx.Columns {
+			covers = covers || column == name
+		}
+		if !(idx.Constraint && !idx.Primary && covers) {
+			indexes = append(indexes, idx)
+		}
+	}
+	table.dependents.indexes = indexes
+}
+
+// removeIndex removes the index called name
+func (table *sqliteTable) removeIndex(name string) {
+	for i, idx := range table.dependents.indexes {
+		if idx.Name == name {
+			table.dependents.indexes = append(table.dependents.indexes[:i:i], table.dependents.indexes[i+1:]...)
+			return
+		}
+	}
+}
+
+// rebuildSQL renders the statements that rebuild stmt's table from one shape into another, copying the
+// columns both have and those renamed, which renamed maps to their name in from
+func (d sqliteDialect) rebuildSQL(m *Migrator, stmt *gorm.Statement, from, to sqliteTable, renamed map[string]string, triggers, views []sqliteObject) string {
+	schemaName, table := d.schemaAndTable(stmt)
+	newTable := "_" + table + "_new"
+	if schemaName != "main" {
+		newTable = schemaName + "." + newTable
+	}
+
+	var createTableSQL, dependentsSQL string
+	_ = m.RunWithValue(newTable, func(newStmt *gorm.Statement) error {
+		createTableSQL, _ = m.catalogTableSQL(newStmt, to.columns, to.dependents)
+		return nil
+	})
+	_, dependentsSQL = m.catalogTableSQL(stmt, to.columns, to.dependents)
+
+	existing := map[string]bool{}
+	for _, column := range from.columns {
+		existing[column.Name()] = true
+	}
+	var copied, sources []string
+	for _, column := range to.columns {
+		source := column.Name()
+		if previous, ok := renamed[source]; ok {
+			source = previous
+		}
+		if existing[source] {
+			copied = append(copied, column.Name())
+			sources = append(sources, source)
+		}
+	}
+
+	sql := "-- Rebuild Table \n" + createTableSQL
+	if len(copied) > 0 {
+		selectList := strings.TrimSuffix(strings.Repeat("?,", len(copied)), ",")
+		values := append([]interface{}{clause.Table{Name: newTable}, columnList(copied)}, columnList(sources)...)
+		sql += buildRawSQL(m.DB, "INSERT INTO ? ? SELECT "+selectList+" FROM ?", append(values, m.CurrentTable(stmt))...)
+	}
+	for _, view := range views {
+		name := view.name
+		if schemaName != "main" {
+			name = schemaName + "." + name
+		}
+		sql += buildRawSQL(m.DB, "DROP VIEW ?", clause.Table{Name: name})
+	}
+	sql += buildRawSQL(m.DB, "DROP TABLE ?", m.CurrentTable(stmt))
+	sql += buildRawSQL(m.DB, "ALTER TABLE ? RENAME TO ?", clause.Table{Name: newTable}, clause.Table{Name: table})
+	sql += dependentsSQL
+	for _, trigger := range triggers {
+		sql += trigger.sql + "; \n"
+	}
+	for _, view := range views {
+		sql += view.sql + "; \n"
+	}
+	return sql
+}
+
+// ResetSchema drops the views, which would outlive the tables they select from, then the tables, their
+// indexes and triggers go with them
+func (sqliteDialect) ResetSchema(m *Migrator) error {
+	for _, kind := range []string{"view", "table"} {
+		var names []string
+		if err := m.DB.Raw("SELECT name FROM sqlite_master WHERE type = ? AND name NOT LIKE 'sqlite_%'", kind).Scan(&names).Error; err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := m.DB.Exec("DROP "+strings.ToUpper(kind)+" ?", clause.Table{Name: name}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (sqliteDialect) BeginSQL() string {
+	return "BEGIN;"
+}
+
+func (sqliteDialect) MigrateDriver(db *sql.DB) (database.Driver, error) {
+	// migrations open their own transaction, a table rebuild turns foreign keys off outside of it
+	return sqlite.WithInstance(db, &sqlite.Config{NoTxWrap: true})
+}
+
+// NormalizeType returns the affinity SQLite gives a declared type, any type name is accepted and only the
+// affinity decides how values are stored
+func (sqliteDialect) NormalizeType(columnType string) string {
+	columnType = baseType(columnType)
+	switch {
+	case strings.Contains(columnType, "int"):
+		return "integer"
+	case strings.Contains(columnType, "char"), strings.Contains(columnType, "clob"), strings.Contains(columnType, "text"):
+		return "text"
+	case strings.Contains(columnType, "blob"), columnType == "":
+		return "blob"
+	case strings.Contains(columnType, "real"), strings.Contains(columnType, "floa"), strings.Contains(columnType, "doub"):
+		return "real"
+	}
+	return "numeric"
+}
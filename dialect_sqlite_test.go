@@ -0,0 +1,66 @@
+package migrator
+
+import (
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestSQLiteDialect(t *testing.T) {
+	testDialectIntrospection(t, openSQLite(t), "main")
+}
+
+func TestSQLiteAlterColumn(t *testing.T) {
+	testAlterColumnRoundTrip(t, testMigrator(t, openSQLite(t)), &alterItem{}, &alterItemChanged{})
+}
+
+// closedSQLite returns a SQLite database every query fails on, like one that cannot be reached
+func closedSQLite(t *testing.T) *gorm.DB {
+	db := openSQLite(t)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+	return db
+}
+
+func TestSQLiteOffline(t *testing.T) {
+	tests := []struct {
+		name          string
+		version       string
+		before, after []interface{}
+		up            string
+	}{
+		{
+			name:   "drop column in place",
+			before: []interface{}{&planAuthorWithBio{}},
+			after:  []interface{}{&planAuthor{}},
+			up:     "ALTER TABLE `plan_authors` DROP COLUMN `bio`;",
+		},
+		{
+			name:    "drop column before 3.35",
+			version: "3.31.0",
+			before:  []interface{}{&planAuthorWithBio{}},
+			after:   []interface{}{&planAuthor{}},
+			up:      "-- Rebuild Table",
+		},
+		{
+			name:   "rebuild",
+			before: []interface{}{&planAuthor{}},
+			after:  []interface{}{&planAuthorWiderName{}},
+			up:     "-- Rebuild Table",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testMigrator(t, closedSQLite(t))
+			m.Offline, m.SQLiteVersion = true, tt.version
+			up, _ := renderBetween(t, m, tt.before, tt.after)
+			if !strings.Contains(up, tt.up) {
+				t.Errorf("up migration:\n%s\nwant %s", up, tt.up)
+			}
+		})
+	}
+}
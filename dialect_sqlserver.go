@@ -0,0 +1,498 @@
+package migrator
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/sqlserver"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var regSQLServerVariable = regexp.MustCompile(`\W+`)
+
+type sqlserverDialect struct{}
+
+// schemaAndTable splits a `schema.table` name, defaulting to the user's default schema
+func (sqlserverDialect) schemaAndTable(stmt *gorm.Statement) (interface{}, string) {
+	if tables := strings.Split(stmt.Table, "."); len(tables) == 2 {
+		return tables[0], tables[1]
+	}
+	return clause.Expr{SQL: "SCHEMA_NAME()"}, stmt.Table
+}
+
+func (sqlserverDialect) CurrentDatabase(m *Migrator) (name string, err error) {
+	err = m.DB.Raw("SELECT DB_NAME()").Row().Scan(&name)
+	return
+}
+
+func (d sqlserverDialect) HasTable(m *Migrator, stmt *gorm.Statement) (bool, error) {
+	var count int64
+	schemaName, table := d.schemaAndTable(stmt)
+	err := m.DB.Raw("SELECT count(*) FROM sys.tables t JOIN sys.schemas s ON s.schema_id = t.schema_id WHERE s.name = ? AND t.name = ?", schemaName, table).Row().Scan(&count)
+	return count > 0, err
+}
+
+func (d sqlserverDialect) Tables(m *Migrator, stmt *gorm.Statement) (tables []string, err error) {
+	schemaName, _ := d.schemaAndTable(stmt)
+	err = m.DB.Raw("SELECT t.name FROM sys.tables t JOIN sys.schemas s ON s.schema_id = t.schema_id WHERE s.name = ? AND t.is_ms_shipped = 0 ORDER BY t.name", schemaName).Scan(&tables).Error
+	return
+}
+
+func (d sqlserverDialect) HasIndex(m *Migrator, stmt *gorm.Statement, name string) (bool, error) {
+	var count int64
+	schemaName, table := d.schemaAndTable(stmt)
+	err := m.DB.Raw(`SELECT count(*) FROM sys.indexes i
+JOIN sys.tables t ON t.object_id = i.object_id
+JOIN sys.schemas s ON s.schema_id = t.schema_id
+WHERE s.name = ? AND t.name = ? AND i.name = ?`, schemaName, table, name).Row().Scan(&count)
+	return count > 0, err
+}
+
+// sqlserverTypeSizes are the sizes in bits of fixed size types, the way gorm sizes them
+var sqlserverTypeSizes = map[string]int64{
+	"tinyint":  8,
+	"smallint": 16,
+	"int":      32,
+	"bigint":   64,
+	"real":     32,
+	"float":    64,
+}
+
+func (d sqlserverDialect) ColumnTypes(m *Migrator, stmt *gorm.Statement) ([]ColumnType, error) {
+	schemaName, table := d.schemaAndTable(stmt)
+	rows, err := m.DB.Raw(`SELECT c.name, ty.name, c.max_length, c.precision, c.scale, c.is_nullable, c.is_identity, dc.definition,
+	CASE WHEN EXISTS (SELECT 1 FROM sys.indexes i JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+		WHERE i.object_id = c.object_id AND i.is_primary_key = 1 AND ic.column_id = c.column_id) THEN 1 ELSE 0 END,
+	CASE WHEN EXISTS (SELECT 1 FROM sys.indexes i JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+		WHERE i.object_id = c.object_id AND i.is_unique_constraint = 1 AND ic.column_id = c.column_id
+		AND (SELECT count(*) FROM sys.index_columns ic2 WHERE ic2.object_id = i.object_id AND ic2.index_id = i.index_id) = 1) THEN 1 ELSE 0 END
+FROM sys.columns c
+JOIN sys.tables t ON t.object_id = c.object_id
+JOIN sys.schemas s ON s.schema_id = t.schema_id
+JOIN sys.types ty ON ty.user_type_id = c.user_type_id
+LEFT JOIN sys.default_constraints dc ON dc.parent_object_id = c.object_id AND dc.parent_column_id = c.column_id
+WHERE s.name = ? AND t.name = ?
+ORDER BY c.column_id`, schemaName, table).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columnTypes []ColumnType
+	for rows.Next() {
+		var (
+			column                  ColumnType
+			maxLength               int64
+			precision, scale        int64
+			primaryKey, uniqueValue int64
+		)
+		if err := rows.Scan(&column.NameValue, &column.DataTypeValue, &maxLength, &precision, &scale,
+			&column.NullableValue, &column.AutoIncrementValue, &column.DefaultValueValue, &primaryKey, &uniqueValue); err != nil {
+			return nil, err
+		}
+
+		dataType := strings.ToLower(column.DataTypeValue.String)
+		columnType := dataType
+		switch dataType {
+		case "char", "varchar", "binary", "varbinary", "nchar", "nvarchar":
+			// max_length counts bytes, two for each character of the national types
+			length := maxLength
+			if strings.HasPrefix(dataType, "n") && length > 0 {
+				length /= 2
+			}
+			if length < 0 {
+				columnType += "(MAX)"
+				length = math.MaxInt32
+			} else {
+				columnType += fmt.Sprintf("(%d)", length)
+			}
+			column.LengthValue = sql.NullInt64{Int64: length, Valid: true}
+		case "decimal", "numeric":
+			columnType += fmt.Sprintf("(%d,%d)", precision, scale)
+			column.DecimalSizeValue = sql.NullInt64{Int64: precision, Valid: true}
+			column.ScaleValue = sql.NullInt64{Int64: scale, Valid: true}
+		case "datetime2", "datetimeoffset", "time":
+			columnType += fmt.Sprintf("(%d)", scale)
+			column.DecimalSizeValue = sql.NullInt64{Int64: scale, Valid: true}
+		default:
+			if size, ok := sqlserverTypeSizes[dataType]; ok {
+				column.LengthValue = sql.NullInt64{Int64: size, Valid: true}
+			}
+		}
+		column.ColumnTypeValue = sql.NullString{String: columnType, Valid: true}
+
+		if column.DefaultValueValue.Valid {
+			column.DefaultValueValue.String = sqlserverDefault(column.DefaultValueValue.String)
+		}
+
+		column.PrimaryKeyValue = sql.NullBool{Bool: primaryKey == 1, Valid: true}
+		column.UniqueValue = sql.NullBool{Bool: uniqueValue == 1, Valid: true}
+		column.ScanTypeValue = scanTypeOf(dataType, column.NullableValue.Bool)
+		columnTypes = append(columnTypes, column)
+	}
+
+	return columnTypes, rows.Err()
+}
+
+// sqlserverDefault strips the parentheses SQL Server wraps a default constraint's definition in, and the
+// quotes of a string literal
+func sqlserverDefault(definition string) string {
+	for enclosed(definition) {
+		definition = definition[1 : len(definition)-1]
+	}
+	if strings.HasPrefix(definition, "N'") {
+		definition = definition[1:]
+	}
+	return unquoteDefault(definition)
+}
+
+// enclosed reports whether expr is wrapped in a single pair of parentheses, unlike `(a) + (b)`
+func enclosed(expr string) bool {
+	if !strings.HasPrefix(expr, "(") || !strings.HasSuffix(expr, ")") {
+		return false
+	}
+	depth := 0
+	for i := 0; i < len(expr)-1; i++ {
+		switch expr[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if depth == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (d sqlserverDialect) Indexes(m *Migrator, stmt *gorm.Statement) ([]Index, error) {
+	schemaName, table := d.schemaAndTable(stmt)
+	rows, err := m.DB.Raw(`SELECT i.name, i.is_unique, i.is_primary_key, i.is_unique_constraint, i.type_desc,
+	COALESCE(i.filter_definition, ''), c.name, ic.is_descending_key, ic.is_included_column
+FROM sys.indexes i
+JOIN sys.tables t ON t.object_id = i.object_id
+JOIN sys.schemas s ON s.schema_id = t.schema_id
+JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+WHERE s.name = ? AND t.name = ? AND i.name IS NOT NULL
+ORDER BY i.name, ic.is_included_column, ic.key_ordinal, ic.index_column_id`, schemaName, table).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		indexes  []Index
+		kinds    []string
+		included [][]string
+	)
+	for rows.Next() {
+		var (
+			idx                    = Index{Table: stmt.Table}
+			kind, column           string
+			descending, isIncluded bool
+		)
+		if err := rows.Scan(&idx.Name, &idx.Unique, &idx.Primary, &idx.Constraint, &kind, &idx.Where, &column, &descending, &isIncluded); err != nil {
+			return nil, err
+		}
+		idx.Constraint = idx.Constraint || idx.Primary
+		if len(indexes) == 0 || indexes[len(indexes)-1].Name != idx.Name {
+			indexes = append(indexes, idx)
+			kinds = append(kinds, kind)
+			included = append(included, nil)
+		}
+		last := &indexes[len(indexes)-1]
+		if isIncluded {
+			included[len(included)-1] = append(included[len(included)-1], column)
+			continue
+		}
+		last.Columns = append(last.Columns, column)
+		key := column
+		if descending {
+			key += " DESC"
+		}
+		last.Keys = append(last.Keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// SQL Server has no function that returns an index's definition, rebuild it from the catalog
+	for i := range indexes {
+		idx := &indexes[i]
+		sql := "CREATE "
+		if idx.Unique {
+			sql += "UNIQUE "
+		}
+		sql += strings.ReplaceAll(kinds[i], "_", " ") + " INDEX ? ON ? ("
+		values := []interface{}{clause.Column{Name: idx.Name}, m.CurrentTable(stmt)}
+		for j, key := range idx.Keys {
+			if j > 0 {
+				sql += ","
+			}
+			sql += "?"
+			if strings.HasSuffix(key, " DESC") {
+				sql += " DESC"
+			}
+			values = append(values, clause.Column{Name: idx.Columns[j]})
+		}
+		sql += ")"
+		if len(included[i]) > 0 {
+			sql += " INCLUDE ?"
+			values = append(values, columnList(included[i]))
+		}
+		if idx.Where != "" {
+			sql += " WHERE " + idx.Where
+		}
+		idx.Definition = strings.TrimSuffix(buildRawSQL(m.DB, sql, values...), "; \n")
+	}
+	return indexes, nil
+}
+
+func (d sqlserverDialect) ForeignKeys(m *Migrator, stmt *gorm.Statement) ([]ForeignKey, error) {
+	schemaName, table := d.schemaAndTable(stmt)
+	rows, err := m.DB.Raw(`SELECT fk.name, c.name,
+	CASE WHEN rs.schema_id = s.schema_id THEN rt.name ELSE rs.name + '.' + rt.name END,
+	rc.name, fk.delete_referential_action_desc, fk.update_referential_action_desc
+FROM sys.foreign_keys fk
+JOIN sys.tables t ON t.object_id = fk.parent_object_id
+JOIN sys.schemas s ON s.schema_id = t.schema_id
+JOIN sys.tables rt ON rt.object_id = fk.referenced_object_id
+JOIN sys.schemas rs ON rs.schema_id = rt.schema_id
+JOIN sys.foreign_key_columns fkc ON fkc.constraint_object_id = fk.object_id
+JOIN sys.columns c ON c.object_id = fkc.parent_object_id AND c.column_id = fkc.parent_column_id
+JOIN sys.columns rc ON rc.object_id = fkc.referenced_object_id AND rc.column_id = fkc.referenced_column_id
+WHERE s.name = ? AND t.name = ?
+ORDER BY fk.name, fkc.constraint_column_id`, schemaName, table).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foreignKeys []ForeignKey
+	for rows.Next() {
+		var (
+			fk                       = ForeignKey{Table: stmt.Table}
+			column, referencedColumn string
+		)
+		if err := rows.Scan(&fk.Name, &column, &fk.ReferencedTable, &referencedColumn, &fk.OnDelete, &fk.OnUpdate); err != nil {
+			return nil, err
+		}
+		// actions are reported like NO_ACTION and SET_NULL
+		fk.OnDelete = strings.ReplaceAll(fk.OnDelete, "_", " ")
+		fk.OnUpdate = strings.ReplaceAll(fk.OnUpdate, "_", " ")
+		if len(foreignKeys) == 0 || foreignKeys[len(foreignKeys)-1].Name != fk.Name {
+			foreignKeys = append(foreignKeys, fk)
+		}
+		last := &foreignKeys[len(foreignKeys)-1]
+		last.Columns = append(last.Columns, column)
+		last.ReferencedColumns = append(last.ReferencedColumns, referencedColumn)
+	}
+
+	return foreignKeys, rows.Err()
+}
+
+func (d sqlserverDialect) Checks(m *Migrator, stmt *gorm.Statement) ([]Check, error) {
+	schemaName, table := d.schemaAndTable(stmt)
+	rows, err := m.DB.Raw(`SELECT cc.name, cc.definition, COALESCE(c.name, '')
+FROM sys.check_constraints cc
+JOIN sys.tables t ON t.object_id = cc.parent_object_id
+JOIN sys.schemas s ON s.schema_id = t.schema_id
+LEFT JOIN sys.columns c ON c.object_id = cc.parent_object_id AND c.column_id = cc.parent_column_id
+WHERE s.name = ? AND t.name = ?
+ORDER BY cc.name`, schemaName, table).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checks []Check
+	for rows.Next() {
+		var (
+			chk    = Check{Table: stmt.Table}
+			column string
+		)
+		if err := rows.Scan(&chk.Name, &chk.Expression, &column); err != nil {
+			return nil, err
+		}
+		if column != "" {
+			chk.Columns = []string{column}
+		}
+		checks = append(checks, chk)
+	}
+
+	return checks, rows.Err()
+}
+
+func (sqlserverDialect) ColumnDefinition(m *Migrator, column ColumnType) string {
+	dataType, _ := column.ColumnType()
+	if autoIncrement, _ := column.AutoIncrement(); autoIncrement {
+		dataType += " IDENTITY(1,1)"
+	}
+	if nullable, ok := column.Nullable(); ok && !nullable {
+		dataType += " NOT NULL"
+	}
+	if value, ok := defaultLiteral(column); ok {
+		dataType += " DEFAULT " + value
+	}
+	if unique, _ := column.Unique(); unique {
+		dataType += " UNIQUE"
+	}
+	return dataType
+}
+
+func (sqlserverDialect) ColumnCommentSQL(m *Migrator, stmt *gorm.Statement, column ColumnType) string {
+	return ""
+}
+
+func (d sqlserverDialect) AddColumnSQL(m *Migrator, stmt *gorm.Statement, column ColumnType) string {
+	return addColumnSQL(m, stmt, d, column)
+}
+
+// dropColumnConstraintSQL drops the constraint of column the catalog query finds, if there is one. Default
+// and UNIQUE constraints declared inline get generated names, so the name is looked up when the migration
+// runs, through a variable named after kind, the table and the column.
+func (sqlserverDialect) dropColumnConstraintSQL(m *Migrator, stmt *gorm.Statement, kind, column, query string) string {
+	variable := "@" + kind + "_" + regSQLServerVariable.ReplaceAllString(stmt.Table+"_"+column, "_")
+	if len(variable) > 128 {
+		variable = variable[:128]
+	}
+	// buildRawSQL would take the variable for a named argument
+	query = strings.TrimSuffix(buildRawSQL(m.DB, query, clause.Expr{SQL: quoteString(stmt.Table)}, clause.Expr{SQL: quoteString(column)}), "; \n")
+	return "DECLARE " + variable + " nvarchar(max) = (" + query + "); \n" +
+		"IF " + variable + " IS NOT NULL EXEC(" + variable + "); \n"
+}
+
+// dropDefaultSQL drops the default constraint of column
+func (d sqlserverDialect) dropDefaultSQL(m *Migrator, stmt *gorm.Statement, column string) string {
+	return d.dropColumnConstraintSQL(m, stmt, "default", column, `SELECT 'ALTER TABLE ' + QUOTENAME(OBJECT_SCHEMA_NAME(dc.parent_object_id)) + '.' + QUOTENAME(OBJECT_NAME(dc.parent_object_id)) + ' DROP CONSTRAINT ' + QUOTENAME(dc.name)
+FROM sys.default_constraints dc
+JOIN sys.columns c ON c.object_id = dc.parent_object_id AND c.column_id = dc.parent_column_id
+WHERE dc.parent_object_id = OBJECT_ID(?) AND c.name = ?`)
+}
+
+// dropUniqueSQL drops the single column UNIQUE constraint of column
+func (d sqlserverDialect) dropUniqueSQL(m *Migrator, stmt *gorm.Statement, column string) string {
+	return d.dropColumnConstraintSQL(m, stmt, "unique", column, `SELECT TOP 1 'ALTER TABLE ' + QUOTENAME(OBJECT_SCHEMA_NAME(i.object_id)) + '.' + QUOTENAME(OBJECT_NAME(i.object_id)) + ' DROP CONSTRAINT ' + QUOTENAME(i.name)
+FROM sys.indexes i
+JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+WHERE i.object_id = OBJECT_ID(?) AND i.is_unique_constraint = 1 AND c.name = ?
+AND (SELECT count(*) FROM sys.index_columns ic2 WHERE ic2.object_id = i.object_id AND ic2.index_id = i.index_id) = 1`)
+}
+
+// addDefaultSQL adds column's default as a constraint named DF_<table>_<column>
+func (d sqlserverDialect) addDefaultSQL(m *Migrator, stmt *gorm.Statement, column ColumnType) string {
+	value, ok := defaultLiteral(column)
+	if !ok {
+		return ""
+	}
+	_, table := d.schemaAndTable(stmt)
+	return buildRawSQL(m.DB, "ALTER TABLE ? ADD CONSTRAINT ? DEFAULT ? FOR ?", m.CurrentTable(stmt),
+		clause.Column{Name: "DF_" + table + "_" + column.Name()}, clause.Expr{SQL: value}, clause.Column{Name: column.Name()})
+}
+
+func (d sqlserverDialect) AlterColumnSQL(m *Migrator, stmt *gorm.Statement, column ColumnType, changes ColumnChanges) string {
+	var sql string
+	name := clause.Column{Name: column.Name()}
+	if changes.Type || changes.Nullable {
+		// ALTER COLUMN restates the type and nullability together, and fails while a default depends on the column
+		dataType, _ := column.ColumnType()
+		nullability := "NULL"
+		if nullable, ok := column.Nullable(); ok && !nullable {
+			nullability = "NOT NULL"
+		}
+		sql += d.dropDefaultSQL(m, stmt, column.Name())
+		sql += buildRawSQL(m.DB, "ALTER TABLE ? ALTER COLUMN ? ? "+nullability, m.CurrentTable(stmt), name, clause.Expr{SQL: dataType})
+		sql += d.addDefaultSQL(m, stmt, column)
+	} else if changes.Default {
+		sql += d.dropDefaultSQL(m, stmt, column.Name())
+		sql += d.addDefaultSQL(m, stmt, column)
+	}
+	if changes.Unique {
+		if unique, _ := column.Unique(); unique {
+			_, table := d.schemaAndTable(stmt)
+			constraint := clause.Column{Name: "UQ_" + table + "_" + column.Name()}
+			sql += buildRawSQL(m.DB, "ALTER TABLE ? ADD CONSTRAINT ? UNIQUE (?)", m.CurrentTable(stmt), constraint, name)
+		} else {
+			sql += d.dropUniqueSQL(m, stmt, column.Name())
+		}
+	}
+	return sql
+}
+
+func (d sqlserverDialect) DropColumnSQL(m *Migrator, stmt *gorm.Statement, name string) string {
+	// the column's default and UNIQUE constraints have to go first
+	return d.dropDefaultSQL(m, stmt, name) + d.dropUniqueSQL(m, stmt, name) + dropColumnSQL(m, stmt, name)
+}
+
+func (sqlserverDialect) RenameTableSQL(m *Migrator, stmt *gorm.Statement, name string) string {
+	return buildRawSQL(m.DB, "EXEC sp_rename ?, ?", clause.Expr{SQL: quoteString(stmt.Table)}, clause.Expr{SQL: quoteString(unqualifiedTable(name))})
+}
+
+func (sqlserverDialect) RenameColumnSQL(m *Migrator, stmt *gorm.Statement, column ColumnType, name string) string {
+	// constraints and indexes follow the column, a default constraint keeps the name it was given
+	return buildRawSQL(m.DB, "EXEC sp_rename ?, ?, 'COLUMN'", clause.Expr{SQL: quoteString(stmt.Table + "." + column.Name())}, clause.Expr{SQL: quoteString(name)})
+}
+
+func (sqlserverDialect) CreateIndexSQL(m *Migrator, stmt *gorm.Statement, idx *schema.Index) string {
+	// the index type, CLUSTERED or NONCLUSTERED, goes between UNIQUE and INDEX, and there is nowhere to keep a comment
+	plain := *idx
+	if plain.Type != "" {
+		plain.Class = strings.TrimSpace(plain.Class + " " + strings.ToUpper(plain.Type))
+	}
+	plain.Type, plain.Comment = "", ""
+	return createIndexSQL(m, stmt, &plain, false)
+}
+
+func (sqlserverDialect) DropIndexSQL(m *Migrator, stmt *gorm.Statement, idx Index) string {
+	return buildRawSQL(m.DB, "DROP INDEX ? ON ?", clause.Column{Name: idx.Name}, m.CurrentTable(stmt))
+}
+
+func (sqlserverDialect) AddForeignKeySQL(m *Migrator, stmt *gorm.Statement, fk ForeignKey) string {
+	return addForeignKeySQL(m, stmt, fk)
+}
+
+func (sqlserverDialect) DropForeignKeySQL(m *Migrator, stmt *gorm.Statement, fk ForeignKey) string {
+	return buildRawSQL(m.DB, "ALTER TABLE ? DROP CONSTRAINT ?", m.CurrentTable(stmt), clause.Column{Name: fk.Name})
+}
+
+func (sqlserverDialect) AddCheckSQL(m *Migrator, stmt *gorm.Statement, chk Check) string {
+	return addCheckSQL(m, stmt, chk)
+}
+
+func (sqlserverDialect) DropCheckSQL(m *Migrator, stmt *gorm.Statement, chk Check) string {
+	return buildRawSQL(m.DB, "ALTER TABLE ? DROP CONSTRAINT ?", m.CurrentTable(stmt), clause.Column{Name: chk.Name})
+}
+
+func (sqlserverDialect) BeginSQL() string {
+	return "BEGIN TRANSACTION;"
+}
+
+func (sqlserverDialect) MigrateDriver(db *sql.DB) (database.Driver, error) {
+	return sqlserver.WithInstance(db, &sqlserver.Config{})
+}
+
+// sqlserverTypeAliases maps type names to the names sys.types knows them by
+var sqlserverTypeAliases = map[string]string{
+	"integer":                    "int",
+	"numeric":                    "decimal",
+	"dec":                        "decimal",
+	"double precision":           "float",
+	"character varying":          "varchar",
+	"character":                  "char",
+	"national character varying": "nvarchar",
+	"national character":         "nchar",
+	"rowversion":                 "timestamp",
+}
+
+func (sqlserverDialect) NormalizeType(columnType string) string {
+	return normalizeAlias(columnType, sqlserverTypeAliases)
+}
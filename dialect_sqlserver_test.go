@@ -0,0 +1,96 @@
+//go:build integration
+
+package migrator
+
+import (
+	"testing"
+
+	"gorm.io/driver/sqlserver"
+)
+
+// openSQLServer opens the database MIGRATOR_TEST_SQLSERVER_DSN points at, like one started with
+// docker run -e ACCEPT_EULA=Y -e MSSQL_SA_PASSWORD=Test-1234 -p 1433:1433 mcr.microsoft.com/mssql/server
+func openSQLServer(t *testing.T) *Migrator {
+	db := openIntegration(t, "MIGRATOR_TEST_SQLSERVER_DSN", sqlserver.Open)
+	return testMigrator(t, db)
+}
+
+func TestSQLServerDialect(t *testing.T) {
+	m := openSQLServer(t)
+	var database string
+	if err := m.DB.Raw("SELECT DB_NAME()").Row().Scan(&database); err != nil {
+		t.Fatal(err)
+	}
+	testDialectIntrospection(t, m.DB, database)
+}
+
+// assertVersion fails the test unless the golang-migrate driver of m's dialect reads version as the
+// migrated one
+func assertVersion(t *testing.T, m *Migrator, version int) {
+	t.Helper()
+	d, err := m.dialect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := m.DB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the driver is left open, closing it closes sqlDB too
+	driver, err := d.MigrateDriver(sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, dirty, err := driver.Version(); err != nil || got != version || dirty {
+		t.Errorf("Version() = %d, %v, %v, want %d", got, dirty, err, version)
+	}
+}
+
+type sqlserverSku struct {
+	ID     uint
+	Code   string `gorm:"size:16;unique"`
+	Status string `gorm:"size:16;default:'draft'"`
+}
+
+func (sqlserverSku) TableName() string { return "sqlserver_skus" }
+
+type sqlserverSkuPlain struct {
+	ID     uint
+	Code   string `gorm:"size:16"`
+	Status string `gorm:"size:16"`
+}
+
+func (sqlserverSkuPlain) TableName() string { return "sqlserver_skus" }
+
+func TestSQLServerAlterColumn(t *testing.T) {
+	t.Run("type, nullability and default", func(t *testing.T) {
+		testAlterColumnRoundTrip(t, openSQLServer(t), &alterItem{}, &alterItemChanged{})
+	})
+	// SQL Server names the default and unique constraints itself, they are dropped by looking the name up
+	t.Run("drop default and unique", func(t *testing.T) {
+		testAlterColumnRoundTrip(t, openSQLServer(t), &sqlserverSku{}, &sqlserverSkuPlain{})
+	})
+}
+
+func TestSQLServerPlan(t *testing.T) {
+	// the plan cases cover the sp_rename of tables and columns along with every other operation
+	for _, tc := range planCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := openSQLServer(t)
+			version := 1
+			if len(tc.before) > 0 {
+				migrateTo(t, m, tc.before...)
+				version++
+			}
+
+			migrateTo(t, m, tc.after...)
+			assertNoDrift(t, m)
+			assertVersion(t, m, version)
+			mustRun(t, m, "down")
+			m.Models = tc.before
+			if len(tc.before) > 0 {
+				assertNoDrift(t, m)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package migrator
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type dialectAccount struct {
+	ID      uint
+	Email   string  `gorm:"size:64;not null;uniqueIndex:idx_dialect_accounts_email"`
+	Code    string  `gorm:"size:16;unique"`
+	Balance float64 `gorm:"type:decimal(10,2)"`
+	Visits  int     `gorm:"not null;default:3"`
+	Note    *string `gorm:"size:255"`
+}
+
+func (dialectAccount) TableName() string { return "dialect_accounts" }
+
+// testDialectIntrospection checks the catalog reads of db's dialect against a migrated table, database is
+// the name CurrentDatabase should return
+func testDialectIntrospection(t *testing.T, db *gorm.DB, database string) {
+	m := testMigrator(t, db)
+	migrateTo(t, m, &dialectAccount{})
+	assertNoDrift(t, m)
+
+	t.Run("HasTable", func(t *testing.T) {
+		if has, err := m.hasTable(&dialectAccount{}); err != nil || !has {
+			t.Errorf("HasTable(dialect_accounts) = %v, %v, want true", has, err)
+		}
+		if has, err := m.hasTable("dialect_missing"); err != nil || has {
+			t.Errorf("HasTable(dialect_missing) = %v, %v, want false", has, err)
+		}
+	})
+
+	t.Run("HasIndex", func(t *testing.T) {
+		if has, err := m.hasIndex(&dialectAccount{}, "idx_dialect_accounts_email"); err != nil || !has {
+			t.Errorf("HasIndex(idx_dialect_accounts_email) = %v, %v, want true", has, err)
+		}
+		if has, err := m.hasIndex(&dialectAccount{}, "idx_dialect_missing"); err != nil || has {
+			t.Errorf("HasIndex(idx_dialect_missing) = %v, %v, want false", has, err)
+		}
+	})
+
+	t.Run("CurrentDatabase", func(t *testing.T) {
+		if got := m.CurrentDatabase(); got != database {
+			t.Errorf("CurrentDatabase() = %q, want %q", got, database)
+		}
+	})
+
+	t.Run("ColumnTypes", func(t *testing.T) {
+		columns, err := m.ColumnTypes(&dialectAccount{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		byName := map[string]ColumnType{}
+		for _, column := range columns {
+			names = append(names, column.Name())
+			byName[column.Name()] = column
+		}
+		if got, want := strings.Join(names, ","), "id,email,code,balance,visits,note"; got != want {
+			t.Fatalf("columns = %s, want %s", got, want)
+		}
+
+		if pk, _ := byName["id"].PrimaryKey(); !pk {
+			t.Error("id is not the primary key")
+		}
+		if autoIncrement, _ := byName["id"].AutoIncrement(); !autoIncrement {
+			t.Error("id does not auto increment")
+		}
+		if nullable, ok := byName["email"].Nullable(); !ok || nullable {
+			t.Errorf("email nullable = %v, %v, want false", nullable, ok)
+		}
+		// SQLite declares every string as text, without a length
+		if columnType, _ := byName["email"].ColumnType(); strings.Contains(columnType, "(") {
+			if length, ok := byName["email"].Length(); !ok || length != 64 {
+				t.Errorf("email length = %v, %v, want 64", length, ok)
+			}
+		}
+		if unique, ok := byName["code"].Unique(); !ok || !unique {
+			t.Errorf("code unique = %v, %v, want true", unique, ok)
+		}
+		if precision, scale, ok := byName["balance"].DecimalSize(); !ok || precision != 10 || scale != 2 {
+			t.Errorf("balance decimal size = %v, %v, %v, want 10, 2", precision, scale, ok)
+		}
+		if value, ok := byName["visits"].DefaultValue(); !ok || !strings.Contains(value, "3") {
+			t.Errorf("visits default = %q, %v, want 3", value, ok)
+		}
+		if nullable, ok := byName["note"].Nullable(); !ok || !nullable {
+			t.Errorf("note nullable = %v, %v, want true", nullable, ok)
+		}
+	})
+
+	t.Run("ExcludedTable", func(t *testing.T) {
+		mustExec(t, db, "CREATE TABLE dialect_unregistered (id int)")
+		if got := m.ExcludedTable(m.Models); strings.Join(got, ",") != "dialect_unregistered" {
+			t.Errorf("ExcludedTable() = %v, want [dialect_unregistered]", got)
+		}
+
+		ignoring := *m
+		ignoring.IgnoreTables = []string{"dialect_*"}
+		if got := ignoring.ExcludedTable(m.Models); len(got) != 0 {
+			t.Errorf("ExcludedTable() ignoring dialect_* = %v, want none", got)
+		}
+	})
+}
+
+type alterItem struct {
+	ID    uint
+	Name  string `gorm:"type:varchar(64)"`
+	Score int32
+	Note  string `gorm:"type:varchar(32);not null;default:'none'"`
+}
+
+func (alterItem) TableName() string { return "alter_items" }
+
+type alterItemChanged struct {
+	ID    uint
+	Name  string `gorm:"type:varchar(128);not null;default:'anonymous'"`
+	Score int64  `gorm:"default:0"`
+	Note  string `gorm:"type:varchar(32)"`
+}
+
+func (alterItemChanged) TableName() string { return "alter_items" }
+
+// describeColumns describes every attribute of the columns of value's table a migration can change
+func describeColumns(t *testing.T, m *Migrator, value interface{}) []string {
+	t.Helper()
+	columns, err := m.ColumnTypes(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var described []string
+	for _, column := range columns {
+		columnType, _ := column.ColumnType()
+		nullable, _ := column.Nullable()
+		defaultValue, hasDefault := column.DefaultValue()
+		primaryKey, _ := column.PrimaryKey()
+		autoIncrement, _ := column.AutoIncrement()
+		unique, _ := column.Unique()
+		comment, _ := column.Comment()
+		described = append(described, fmt.Sprintf("%s %s nullable=%t default=%t:%q primary=%t auto=%t unique=%t comment=%q",
+			column.Name(), columnType, nullable, hasDefault, defaultValue, primaryKey, autoIncrement, unique, comment))
+	}
+	return described
+}
+
+// testAlterColumnRoundTrip alters the columns of the before model's table into those of after, and checks
+// that the down migration restores every attribute the catalog reports
+func testAlterColumnRoundTrip(t *testing.T, m *Migrator, before, after interface{}) {
+	t.Helper()
+	migrateTo(t, m, before)
+	original := describeColumns(t, m, before)
+
+	m.Models = []interface{}{after}
+	plan := mustPlan(t, m)
+	if changeIndex(plan, "alter column") < 0 {
+		t.Fatalf("no column altered in %v", plan.Changes())
+	}
+	migrateTo(t, m, after)
+	assertNoDrift(t, m)
+
+	mustRun(t, m, "down")
+	m.Models = []interface{}{before}
+	assertNoDrift(t, m)
+	if restored := describeColumns(t, m, before); strings.Join(restored, "\n") != strings.Join(original, "\n") {
+		t.Errorf("columns after down:\n\t%s\nwant:\n\t%s", strings.Join(restored, "\n\t"), strings.Join(original, "\n\t"))
+	}
+}
+
+type alterCounter struct {
+	ID   int32 `gorm:"primaryKey;autoIncrement"`
+	Hits int
+}
+
+func (alterCounter) TableName() string { return "alter_counters" }
+
+type alterCounterWide struct {
+	ID   int64 `gorm:"primaryKey;autoIncrement"`
+	Hits int
+}
+
+func (alterCounterWide) TableName() string { return "alter_counters" }
+
+type dialectPerson struct {
+	ID  uint
+	Age int
+}
+
+func (dialectPerson) TableName() string { return "dialect_people" }
+
+type dialectPersonAgeText struct {
+	ID  uint
+	Age string
+}
+
+func (dialectPersonAgeText) TableName() string { return "dialect_people" }
+
+// typelessDialect is a SQLite dialect that takes every type for every other
+type typelessDialect struct {
+	sqliteDialect
+}
+
+func (typelessDialect) NormalizeType(columnType string) string { return "any" }
+
+func TestCustomDialect(t *testing.T) {
+	tests := []struct {
+		name     string
+		migrator func(t *testing.T) *Migrator
+		changes  []string
+	}{
+		{
+			name:     "built-in",
+			migrator: func(t *testing.T) *Migrator { return testMigrator(t, openSQLite(t)) },
+			changes:  []string{"alter column dialect_people.age (type: integer to string)"},
+		},
+		{
+			name: "Config.Dialect",
+			migrator: func(t *testing.T) *Migrator {
+				m := testMigrator(t, openSQLite(t))
+				m.Dialect = typelessDialect{}
+				return m
+			},
+		},
+		{
+			name: "registered",
+			migrator: func(t *testing.T) *Migrator {
+				RegisterDialect("other", typelessDialect{})
+				t.Cleanup(func() {
+					dialectsMu.Lock()
+					defer dialectsMu.Unlock()
+					delete(dialects, "other")
+				})
+				db, err := gorm.Open(otherDialector{sqlite.Open(filepath.Join(t.TempDir(), "test.db"))}, &gorm.Config{Logger: logger.Discard})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return testMigrator(t, db)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tt.migrator(t)
+			migrateTo(t, m, &dialectPerson{})
+			m.Models = []interface{}{&dialectPersonAgeText{}}
+			assertChanges(t, mustPlan(t, m), tt.changes...)
+		})
+	}
+}
+
+func TestRunDialectOfDB(t *testing.T) {
+	// the migrator's database has no dialect, the one migrated has
+	other, err := gorm.Open(otherDialector{sqlite.Open(filepath.Join(t.TempDir(), "test.db"))}, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := testMigrator(t, openSQLite(t), &dialectPerson{})
+	mustRun(t, m, "create", "create_people")
+
+	db := openSQLite(t)
+	m.DB = other
+	if err := m.Run(db, "up"); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if has := db.Migrator().HasTable("dialect_people"); !has {
+		t.Error("dialect_people not created")
+	}
+}
@@ -0,0 +1,94 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrNoCommand is returned by Run when no command is given
+	ErrNoCommand = errors.New("no command specified")
+	// ErrUnknownCommand is returned by Run for a command it does not understand
+	ErrUnknownCommand = errors.New("unknown command")
+	// ErrUnsupportedDialect is returned when no Dialect is registered for the database
+	ErrUnsupportedDialect = errors.New("unsupported dialect")
+	// ErrNoMigrationName is returned by Run when "create" is called without a migration name
+	ErrNoMigrationName = errors.New("no migration name specified")
+	// ErrInvalidArgument is returned by Run for a missing or malformed command argument
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrDestructiveChange is wrapped by the *DestructiveChangeError Run returns for a migration that loses data
+	ErrDestructiveChange = errors.New("migration would lose data")
+	// ErrSchemaDrift is wrapped by the *DriftError Check returns when the database differs from the models
+	ErrSchemaDrift = errors.New("database schema differs from the models")
+	// ErrPossibleRename is wrapped by the *PossibleRenameError Plan returns for a dropped and an added column
+	// that look like a rename
+	ErrPossibleRename = errors.New("column may have been renamed")
+)
+
+// WriteError records a failure to write a migration file and the path it was written to
+type WriteError struct {
+	Path string
+	Err  error
+}
+
+func (e *WriteError) Error() string {
+	return fmt.Sprintf("writing %s: %v", e.Path, e.Err)
+}
+
+func (e *WriteError) Unwrap() error {
+	return e.Err
+}
+
+// DestructiveChangeError is returned by Run when "create" would write a migration that loses data
+// without AllowDestructive or confirmation, it lists the changes responsible
+type DestructiveChangeError struct {
+	Changes []Change
+}
+
+func (e *DestructiveChangeError) Error() string {
+	descriptions := make([]string, 0, len(e.Changes))
+	for _, c := range e.Changes {
+		descriptions = append(descriptions, c.String())
+	}
+	return fmt.Sprintf("%v: %s", ErrDestructiveChange, strings.Join(descriptions, "; "))
+}
+
+func (e *DestructiveChangeError) Unwrap() error {
+	return ErrDestructiveChange
+}
+
+// DriftError is returned by Check when the database differs from the registered models, it lists the
+// changes a migration would make
+type DriftError struct {
+	Changes []Change
+}
+
+func (e *DriftError) Error() string {
+	descriptions := make([]string, 0, len(e.Changes))
+	for _, c := range e.Changes {
+		descriptions = append(descriptions, c.String())
+	}
+	return fmt.Sprintf("%v: %s", ErrSchemaDrift, strings.Join(descriptions, "; "))
+}
+
+func (e *DriftError) Unwrap() error {
+	return ErrSchemaDrift
+}
+
+// PossibleRenameError is returned by Plan when a column dropped from a table and one added to it have
+// compatible types and neither confirmation nor AllowDestructive settles whether it was renamed
+type PossibleRenameError struct {
+	Table string
+	From  string
+	To    string
+}
+
+func (e *PossibleRenameError) Error() string {
+	return fmt.Sprintf("%v: %s.%s to %s, tag the field `migrator:\"renamed_from:%s\"` to rename it or allow destructive changes to replace it",
+		ErrPossibleRename, e.Table, e.From, e.To, e.From)
+}
+
+func (e *PossibleRenameError) Unwrap() error {
+	return ErrPossibleRename
+}
@@ -0,0 +1,105 @@
+package migrator
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// createIndexSQL renders `CREATE INDEX` for idx, usingFirst puts the access method before the key list as
+// Postgres requires, MySQL takes it after
+func createIndexSQL(m *Migrator, stmt *gorm.Statement, idx *schema.Index, usingFirst bool) string {
+	sql := "CREATE "
+	if idx.Class != "" {
+		sql += idx.Class + " "
+	}
+	sql += "INDEX ? ON ?"
+	values := []interface{}{clause.Column{Name: idx.Name}, m.CurrentTable(stmt)}
+
+	if idx.Type != "" && usingFirst {
+		sql += " USING " + idx.Type
+	}
+	sql += " ?"
+	values = append(values, m.BuildIndexOptions(idx.Fields, stmt))
+	if idx.Type != "" && !usingFirst {
+		sql += " USING " + idx.Type
+	}
+
+	if idx.Where != "" {
+		sql += " WHERE " + idx.Where
+	}
+	if idx.Option != "" {
+		sql += " " + idx.Option
+	}
+	return buildRawSQL(m.DB, sql, values...)
+}
+
+// indexKeys lists the key parts of a model index the way Index.Keys does
+func indexKeys(idx *schema.Index) []string {
+	keys := make([]string, 0, len(idx.Fields))
+	for _, opt := range idx.Fields {
+		key := opt.Expression
+		if key == "" {
+			key = opt.DBName
+		}
+		if opt.Length > 0 {
+			key += fmt.Sprintf("(%d)", opt.Length)
+		}
+		if strings.EqualFold(opt.Sort, "desc") {
+			key += " DESC"
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// indexChanges describes how model index idx differs from the live index, nothing when they match
+func indexChanges(live Index, idx *schema.Index) []string {
+	var changes []string
+
+	if live.Keys != nil {
+		modelKeys := indexKeys(idx)
+		same := len(modelKeys) == len(live.Keys)
+		for i := 0; same && i < len(modelKeys); i++ {
+			same = normalizeExpression(modelKeys[i]) == normalizeExpression(live.Keys[i])
+		}
+		if !same {
+			changes = append(changes, fmt.Sprintf("keys (%s) to (%s)", strings.Join(live.Keys, ", "), strings.Join(modelKeys, ", ")))
+		}
+	}
+
+	class := strings.ToUpper(idx.Class)
+	if unique := class == "UNIQUE"; unique != live.Unique {
+		if unique {
+			changes = append(changes, "unique")
+		} else {
+			changes = append(changes, "not unique")
+		}
+	}
+	if class == "UNIQUE" {
+		class = ""
+	}
+	if class != strings.ToUpper(live.Class) {
+		changes = append(changes, fmt.Sprintf("class %q to %q", live.Class, class))
+	}
+
+	// a method is only known where the database has several, and btree is the default everywhere
+	if live.Method != "" {
+		method := strings.ToLower(idx.Type)
+		if method == "" {
+			method = "btree"
+		}
+		if method != strings.ToLower(live.Method) {
+			changes = append(changes, fmt.Sprintf("method %s to %s", live.Method, method))
+		}
+	}
+
+	if normalizeExpression(live.Where) != normalizeExpression(idx.Where) {
+		changes = append(changes, fmt.Sprintf("predicate %q to %q", live.Where, idx.Where))
+	}
+
+	return changes
+}
@@ -1,116 +1,342 @@
 package migrator
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database"
-	"github.com/golang-migrate/migrate/v4/database/mysql"
-	"github.com/golang-migrate/migrate/v4/database/postgres"
-	"github.com/golang-migrate/migrate/v4/database/sqlite"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
-func (mg *Migrator) Run(db *gorm.DB, command string, migrationname ...string) error {
+// Run runs command against db. Supported commands are:
+//
+//	create <name>     generate a migration from the registered models
+//	diff              print the migration "create" would generate without writing it
+//	check             fail when the database differs from the registered models
+//	up [N]            apply all pending migrations, or the next N
+//	down [N]          roll back the last migration, or the last N
+//	clear             roll back every applied migration
+//	goto <version>    migrate up or down to version
+//	force <version>   set version without running migrations, clearing the dirty flag, -1 for none
+//	version           print the current version and dirty flag
+//	drop              drop everything in the database
+//
+// "create" refuses to write a migration that drops tables or columns or narrows
+// column types with a *DestructiveChangeError, unless AllowDestructive is set or
+// Confirm approves it. Next to each migration it writes the Snapshot of the
+// models that Offline generation compares the next one with. "check" returns a
+// *DriftError listing the changes a migration would make.
+//
+// It returns ErrNoCommand, ErrUnknownCommand, ErrUnsupportedDialect,
+// ErrNoMigrationName or ErrInvalidArgument for invalid input, a *WriteError
+// when a migration file cannot be written, and wrapped driver errors otherwise.
+func (mg *Migrator) Run(db *gorm.DB, command string, args ...string) error {
 	var err error
-	var driver database.Driver
 
-	if command == "" {
-		log.Fatal("Specify a Command to run")
+	switch command {
+	case "":
+		return ErrNoCommand
+	case "up", "down", "clear", "create", "diff", "check", "goto", "force", "version", "drop":
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownCommand, command)
 	}
 
-	sqlDB, err := db.DB()
-	if err != nil {
-		log.Fatal("error getting sql.DB representation:", err)
-	}
+	startTime := time.Now()
+	out := mg.output()
 
-	dbName := db.Config.Dialector.Name()
-	switch dbName {
-	case "postgres":
-		driver, err = postgres.WithInstance(sqlDB, &postgres.Config{})
-		if err != nil {
-			log.Fatal("error instantiating postgres instance:", err)
-		}
-	case "mysql":
-		driver, err = mysql.WithInstance(sqlDB, &mysql.Config{})
-		if err != nil {
-			log.Fatal("error instantiating postgres instance:", err)
+	switch command {
+	case "diff":
+		return mg.Diff(out)
+	case "check":
+		if err := mg.Check(); err != nil {
+			return err
 		}
-	case "sqlite":
-		driver, err = sqlite.WithInstance(sqlDB, &sqlite.Config{})
-		if err != nil {
-			log.Fatal("error instantiating postgres instance:", err)
+		fmt.Fprintln(out, "no changes")
+		return nil
+	case "create":
+		if len(args) == 0 || args[0] == "" {
+			return ErrNoMigrationName
 		}
-	default:
-		log.Fatal("Datebase not supported")
+
+		//generate migration
+		return mg.withTarget(func(target *Migrator) error {
+			plan, err := target.Plan()
+			if err != nil {
+				return fmt.Errorf("error generating migration: %w", err)
+			}
+			sqlUp, sqlDown, err := target.Render(plan)
+			if err != nil {
+				return fmt.Errorf("error generating migration: %w", err)
+			}
+			if sqlUp == "" && sqlDown == "" {
+				fmt.Fprintln(out, "no changes")
+				return nil
+			}
+
+			if mg.DryRun {
+				printDestructive(out, plan.Changes())
+				upName, downName := mg.NamingStrategy(mg.migrationPath, args[0], startTime)
+				return mg.printMigration(out, upName, downName, sqlUp, sqlDown)
+			}
+
+			if err := mg.checkDestructive(plan.Changes()); err != nil {
+				return err
+			}
+			if err := mg.createCmd(startTime, args[0], sqlUp, sqlDown); err != nil {
+				return err
+			}
+			fmt.Fprintln(out, "Finished after: ", time.Since(startTime).String())
+			return nil
+		})
 	}
 
-	m, err := migrate.NewWithDatabaseInstance("file://"+mg.migrationPath, dbName, driver)
+	m, err := mg.newMigrate(db)
 	if err != nil {
-		log.Fatal("error instantiating migration instance:", err)
+		return err
 	}
 
-	startTime := time.Now()
-
 	switch command {
 	case "up":
-		err = m.Up()
+		var n int
+		if n, err = countArg(args); err != nil {
+			return err
+		}
+		if n == 0 {
+			err = m.Up()
+		} else {
+			err = m.Steps(n)
+		}
 	case "down":
-		err = m.Steps(-1)
+		var n int
+		if n, err = countArg(args); err != nil {
+			return err
+		}
+		if n == 0 {
+			n = 1
+		}
+		err = m.Steps(-n)
 	case "clear":
 		err = m.Down()
-	case "create":
-		if len(migrationname) == 0 || migrationname[0] == "" {
-			log.Fatal("Specify a name for the migration")
+	case "goto":
+		var v int
+		if v, err = versionArg(command, args); err != nil {
+			return err
+		}
+		err = m.Migrate(uint(v))
+	case "force":
+		var v int
+		if v, err = versionArg(command, args); err != nil {
+			return err
+		}
+		err = m.Force(v)
+	case "version":
+		version, dirty, verr := m.Version()
+		if verr == migrate.ErrNilVersion {
+			fmt.Fprintln(out, "no migration has been applied")
+			return nil
+		}
+		if verr != nil {
+			return fmt.Errorf("error reading version: %w", verr)
 		}
+		fmt.Fprintf(out, "version: %d, dirty: %t\n", version, dirty)
+		return nil
+	case "drop":
+		err = m.Drop()
+	}
 
-		//generate migration
-		sqlUp, sqlDown, err := mg.AutoMigrate()
+	if err != nil {
+		if err != migrate.ErrNoChange {
+			return fmt.Errorf("error running %s: %w", command, err)
+		}
+		fmt.Fprintln(out, err)
+	}
+
+	fmt.Fprintln(out, "Finished after: ", time.Since(startTime).String())
+	return nil
+}
+
+// Diff writes the up and down SQL of the migration "create" would generate to w,
+// without touching the filesystem
+func (mg *Migrator) Diff(w io.Writer) error {
+	return mg.withTarget(func(target *Migrator) error {
+		plan, err := target.Plan()
+		if err != nil {
+			return fmt.Errorf("error generating migration: %w", err)
+		}
+		sqlUp, sqlDown, err := target.Render(plan)
 		if err != nil {
-			log.Fatal(err)
+			return fmt.Errorf("error generating migration: %w", err)
 		}
 		if sqlUp == "" && sqlDown == "" {
+			_, err = fmt.Fprintln(w, "-- no changes")
+			return err
+		}
+		printDestructive(w, plan.Changes())
+		return mg.printMigration(w, "up", "down", sqlUp, sqlDown)
+	})
+}
+
+// Check compares the registered models with the database the way "create" does and returns a *DriftError
+// listing the changes a migration would make, or nil when there are none. It never asks Confirm, a column
+// that may have been renamed is reported as dropped and added.
+func (mg *Migrator) Check() error {
+	checker := *mg
+	checker.Confirm = nil
+	checker.AllowDestructive = true
+	return checker.withTarget(func(target *Migrator) error {
+		plan, err := target.Plan()
+		if err != nil {
+			return fmt.Errorf("error checking schema: %w", err)
+		}
+		if plan.Empty() {
 			return nil
 		}
+		return &DriftError{Changes: plan.Changes()}
+	})
+}
+
+// withTarget calls fn with the migrator whose schema the models are compared with: the latest snapshot in
+// the migration folder when Offline, ShadowDB once the migration folder is applied to it, or DB
+func (mg *Migrator) withTarget(fn func(target *Migrator) error) error {
+	switch {
+	case mg.Offline:
+		return mg.withSnapshot(fn)
+	case mg.ShadowDB != nil:
+		return mg.withShadow(fn)
+	}
+	return fn(mg)
+}
+
+// printMigration writes the up and, when enabled, down SQL to w, each under a comment header
+func (mg *Migrator) printMigration(w io.Writer, upName, downName, sqlUp, sqlDown string) error {
+	if _, err := fmt.Fprintf(w, "-- %s\n%s\n", upName, sqlUp); err != nil {
+		return err
+	}
+	if mg.DownMigrationsEnabled {
+		if _, err := fmt.Fprintf(w, "\n-- %s\n%s\n", downName, sqlDown); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		mg.createCmd(startTime, migrationname[0], sqlUp, sqlDown)
+// printDestructive warns about the destructive changes in a printed migration
+func printDestructive(w io.Writer, changes []Change) {
+	for _, c := range destructiveChanges(changes) {
+		fmt.Fprintf(w, "-- WARNING: destructive change: %s\n", c)
 	}
+}
 
+func (mg *Migrator) output() io.Writer {
+	if mg.Output == nil {
+		return os.Stdout
+	}
+	return mg.Output
+}
+
+// Version returns the currently applied migration version of db and whether it is dirty.
+// It returns migrate.ErrNilVersion when no migration has been applied.
+func (mg *Migrator) Version(db *gorm.DB) (version uint, dirty bool, err error) {
+	m, err := mg.newMigrate(db)
 	if err != nil {
-		if err != migrate.ErrNoChange {
-			log.Fatal(err)
-		} else {
-			fmt.Println(err)
-		}
+		return 0, false, err
 	}
+	return m.Version()
+}
 
-	fmt.Println("Finished after: ", time.Since(startTime).String())
-	return nil
+// newMigrate returns a golang-migrate instance reading from the migration folder
+func (mg *Migrator) newMigrate(db *gorm.DB) (*migrate.Migrate, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error getting sql.DB representation: %w", err)
+	}
+
+	d, err := mg.dialectOf(db)
+	if err != nil {
+		return nil, err
+	}
+	dbName := db.Config.Dialector.Name()
+	driver, err := d.MigrateDriver(sqlDB)
+	if err != nil {
+		return nil, fmt.Errorf("error instantiating %s instance: %w", dbName, err)
+	}
+
+	m, err := migrate.NewWithDatabaseInstance("file://"+mg.migrationPath, dbName, driver)
+	if err != nil {
+		return nil, fmt.Errorf("error instantiating migration instance: %w", err)
+	}
+	return m, nil
 }
 
-func (mg *Migrator) createCmd(timestamp time.Time, name string, sqlUp string, sqlDown string) {
-	_ = os.MkdirAll(mg.migrationPath, os.ModePerm)
+// countArg parses the optional step count of "up" and "down", zero means none was given
+func countArg(args []string) (int, error) {
+	if len(args) == 0 || args[0] == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%w: step count must be a positive integer, got %q", ErrInvalidArgument, args[0])
+	}
+	return n, nil
+}
+
+// versionArg parses the required version of "goto" and "force", "force" also takes -1 to clear the version
+// the way golang-migrate does
+func versionArg(command string, args []string) (int, error) {
+	if len(args) == 0 || args[0] == "" {
+		return 0, fmt.Errorf("%w: missing version", ErrInvalidArgument)
+	}
+	if command == "force" && args[0] == "-1" {
+		return database.NilVersion, nil
+	}
+	v, err := strconv.ParseUint(args[0], 10, 63)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid version %q: %v", ErrInvalidArgument, args[0], errors.Unwrap(err))
+	}
+	return int(v), nil
+}
+
+func (mg *Migrator) createCmd(timestamp time.Time, name string, sqlUp string, sqlDown string) error {
+	if err := os.MkdirAll(mg.migrationPath, os.ModePerm); err != nil {
+		return &WriteError{Path: mg.migrationPath, Err: err}
+	}
+	snapshot, err := mg.Snapshot()
+	if err != nil {
+		return err
+	}
 	upName, downName := mg.NamingStrategy(mg.migrationPath, name, timestamp)
-	createFile(upName, sqlUp)
+	if err := createFile(upName, sqlUp); err != nil {
+		return err
+	}
 
 	if mg.DownMigrationsEnabled {
-		createFile(downName, sqlDown)
+		if err := createFile(downName, sqlDown); err != nil {
+			return err
+		}
 	}
+	// the next migration can be generated offline from the schema this one leaves behind
+	return writeSnapshot(snapshotPath(upName), snapshot)
 }
 
-func createFile(fname string, content string) {
+func createFile(fname string, content string) (err error) {
 	f, err := os.Create(fname)
 	if err != nil {
-		log.Fatal(err)
+		return &WriteError{Path: fname, Err: err}
 	}
-	defer f.Close()
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = &WriteError{Path: fname, Err: cerr}
+		}
+	}()
 	if _, err = f.WriteString(content); err != nil {
-		log.Fatal(err)
+		return &WriteError{Path: fname, Err: err}
 	}
-
+	return nil
 }
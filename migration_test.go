@@ -0,0 +1,259 @@
+package migrator
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestCheck(t *testing.T) {
+	m := testMigrator(t, openSQLite(t))
+	migrateTo(t, m, &planAuthor{})
+	var out bytes.Buffer
+	m.Output = &out
+
+	mustRun(t, m, "check")
+	if got := out.String(); got != "no changes\n" {
+		t.Errorf("check printed %q, want no changes", got)
+	}
+
+	out.Reset()
+	m.Models = []interface{}{&planAuthorWithBio{}}
+	err := m.Run(m.DB, "check")
+	var drift *DriftError
+	if !errors.As(err, &drift) || !errors.Is(err, ErrSchemaDrift) {
+		t.Fatalf("check = %v, want a *DriftError", err)
+	}
+	var changes []string
+	for _, change := range drift.Changes {
+		changes = append(changes, change.String())
+	}
+	if got, want := strings.Join(changes, "\n"), "add column plan_authors.bio"; got != want {
+		t.Errorf("Changes = %s, want %s", got, want)
+	}
+	if out.Len() > 0 {
+		t.Errorf("check printed %q, want nothing", out.String())
+	}
+}
+
+func TestCountArg(t *testing.T) {
+	tests := []struct {
+		args []string
+		want int
+		err  bool
+	}{
+		{args: nil, want: 0},
+		{args: []string{""}, want: 0},
+		{args: []string{"3"}, want: 3},
+		{args: []string{"0"}, err: true},
+		{args: []string{"-1"}, err: true},
+		{args: []string{"two"}, err: true},
+	}
+	for _, tt := range tests {
+		got, err := countArg(tt.args)
+		if tt.err {
+			if !errors.Is(err, ErrInvalidArgument) {
+				t.Errorf("countArg(%q) = %v, want ErrInvalidArgument", tt.args, err)
+			}
+		} else if err != nil || got != tt.want {
+			t.Errorf("countArg(%q) = %d, %v, want %d", tt.args, got, err, tt.want)
+		}
+	}
+}
+
+func TestVersionArg(t *testing.T) {
+	tests := []struct {
+		command string
+		args    []string
+		want    int
+		err     bool
+	}{
+		{command: "goto", args: []string{"1657112223"}, want: 1657112223},
+		{command: "force", args: []string{"0"}, want: 0},
+		// golang-migrate clears the version with -1
+		{command: "force", args: []string{"-1"}, want: -1},
+		{command: "goto", args: []string{"-1"}, err: true},
+		{command: "force", args: []string{"-2"}, err: true},
+		{command: "goto", args: nil, err: true},
+		{command: "force", args: []string{""}, err: true},
+		{command: "goto", args: []string{"latest"}, err: true},
+	}
+	for _, tt := range tests {
+		got, err := versionArg(tt.command, tt.args)
+		if tt.err {
+			if !errors.Is(err, ErrInvalidArgument) {
+				t.Errorf("versionArg(%s, %q) = %v, want ErrInvalidArgument", tt.command, tt.args, err)
+			}
+		} else if err != nil || got != tt.want {
+			t.Errorf("versionArg(%s, %q) = %d, %v, want %d", tt.command, tt.args, got, err, tt.want)
+		}
+	}
+}
+
+func TestSteps(t *testing.T) {
+	m := testMigrator(t, openSQLite(t))
+	for _, models := range [][]interface{}{
+		{&planAuthor{}},
+		{&planAuthorWithBio{}},
+		{&planAuthorWithBio{}, &planBook{}},
+	} {
+		m.Models = models
+		mustRun(t, m, "create", "step")
+		// each migration is generated against the schema the previous ones leave behind
+		mustRun(t, m, "up")
+	}
+	mustRun(t, m, "clear")
+
+	assertAt := func(want uint) {
+		t.Helper()
+		version, dirty, err := m.Version(m.DB)
+		if err != nil || version != want || dirty {
+			t.Fatalf("Version() = %d, %v, %v, want %d", version, dirty, err, want)
+		}
+	}
+	mustRun(t, m, "up", "1")
+	assertAt(1)
+	mustRun(t, m, "up")
+	assertAt(3)
+	mustRun(t, m, "down", "2")
+	assertAt(1)
+	mustRun(t, m, "goto", "3")
+	assertAt(3)
+	mustRun(t, m, "down")
+	assertAt(2)
+
+	var out bytes.Buffer
+	m.Output = &out
+	mustRun(t, m, "version")
+	if got := out.String(); got != "version: 2, dirty: false\n" {
+		t.Errorf("version printed %q", got)
+	}
+
+	mustRun(t, m, "force", "-1")
+	if _, _, err := m.Version(m.DB); !errors.Is(err, migrate.ErrNilVersion) {
+		t.Errorf("Version() after force -1 = %v, want ErrNilVersion", err)
+	}
+	mustRun(t, m, "force", "2")
+	assertAt(2)
+
+	mustRun(t, m, "drop")
+	if tables := tablesOf(t, m.DB); len(tables) > 0 {
+		t.Errorf("tables after drop = %v, want none", tables)
+	}
+}
+
+// otherDialector is a dialector no dialect is registered for
+type otherDialector struct {
+	gorm.Dialector
+}
+
+func (otherDialector) Name() string { return "other" }
+
+func TestRunInvalidInput(t *testing.T) {
+	m := testMigrator(t, openSQLite(t), &planAuthor{})
+	tests := []struct {
+		command string
+		args    []string
+		err     error
+	}{
+		{command: "", err: ErrNoCommand},
+		{command: "migrate", err: ErrUnknownCommand},
+		{command: "create", err: ErrNoMigrationName},
+		{command: "create", args: []string{""}, err: ErrNoMigrationName},
+		{command: "up", args: []string{"all"}, err: ErrInvalidArgument},
+		{command: "down", args: []string{"0"}, err: ErrInvalidArgument},
+		{command: "goto", err: ErrInvalidArgument},
+		{command: "force", args: []string{"dirty"}, err: ErrInvalidArgument},
+	}
+	for _, tt := range tests {
+		if err := m.Run(m.DB, tt.command, tt.args...); !errors.Is(err, tt.err) {
+			t.Errorf("Run(%q, %q) = %v, want %v", tt.command, tt.args, err, tt.err)
+		}
+	}
+	if files := migrationFiles(t, m); len(files) > 0 {
+		t.Errorf("files = %v, want none", files)
+	}
+
+	db, err := gorm.Open(otherDialector{m.DB.Dialector}, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := testMigrator(t, db, &planAuthor{})
+	if err := other.Run(db, "create", "test"); !errors.Is(err, ErrUnsupportedDialect) {
+		t.Errorf("Run(create) on %s = %v, want ErrUnsupportedDialect", db.Dialector.Name(), err)
+	}
+}
+
+func TestRunWriteError(t *testing.T) {
+	m := testMigrator(t, openSQLite(t), &planAuthor{})
+	// a file stands where the migration folder would be created
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m.migrationPath = filepath.Join(file, "migrations")
+
+	err := m.Run(m.DB, "create", "test")
+	var writeErr *WriteError
+	if !errors.As(err, &writeErr) {
+		t.Fatalf("Run(create) = %v, want a *WriteError", err)
+	}
+	if writeErr.Path != m.migrationPath || writeErr.Err == nil {
+		t.Errorf("WriteError = %+v, want one for %s", writeErr, m.migrationPath)
+	}
+}
+
+func TestPrintMigration(t *testing.T) {
+	tests := []struct {
+		name     string
+		run      func(m *Migrator) error
+		sections []string
+	}{
+		{
+			name:     "diff",
+			run:      func(m *Migrator) error { return m.Run(m.DB, "diff") },
+			sections: []string{"-- up\n", "\n-- down\n"},
+		},
+		{
+			name: "dry run",
+			run: func(m *Migrator) error {
+				m.DryRun = true
+				return m.Run(m.DB, "create", "create_authors")
+			},
+			sections: []string{"1_create_authors.up.sql\n", "1_create_authors.down.sql\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testMigrator(t, openSQLite(t), &planAuthor{})
+			var out bytes.Buffer
+			m.Output = &out
+			if err := tt.run(m); err != nil {
+				t.Fatal(err)
+			}
+
+			printed := out.String()
+			up, down := strings.Index(printed, tt.sections[0]), strings.Index(printed, tt.sections[1])
+			if up < 0 || down < up {
+				t.Fatalf("printed:\n%s\nwant the sections %q", printed, tt.sections)
+			}
+			up += len(tt.sections[0])
+			if got := statements(printed[up:down]); len(got) != 1 || !strings.HasPrefix(got[0], "CREATE TABLE `plan_authors`") {
+				t.Errorf("up section = %q, want the table created", got)
+			}
+			if got := statements(printed[down+len(tt.sections[1]):]); len(got) != 1 || got[0] != "DROP TABLE IF EXISTS `plan_authors`;" {
+				t.Errorf("down section = %q, want the table dropped", got)
+			}
+			if files := migrationFiles(t, m); len(files) > 0 {
+				t.Errorf("files = %v, want none", files)
+			}
+		})
+	}
+}
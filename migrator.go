@@ -4,8 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -31,7 +35,7 @@ type namingStrategy func(migrationPath, name string, t time.Time) (string, strin
 
 func defaultNamingStrategy(migrationPath, name string, t time.Time) (string, string) {
 	timestamp := t.Unix()
-	base := fmt.Sprintf("%v%v_%v.", migrationPath, timestamp, name)
+	base := filepath.Join(migrationPath, fmt.Sprintf("%v_%v.", timestamp, name))
 
 	return base + "up.sql", base + "down.sql"
 }
@@ -42,9 +46,47 @@ type Config struct {
 	DB                          *gorm.DB
 	NamingStrategy              namingStrategy
 	DownMigrationsEnabled       bool
+	// DryRun makes "create" print the migration to Output instead of writing files
+	DryRun bool
+	// Output receives everything Run prints, defaults to os.Stdout
+	Output io.Writer
+	// IgnoreTables lists tables AutoMigrate never drops, by name or path.Match glob like "audit_*"
+	IgnoreTables []string
+	// IgnoreTablePatterns lists regular expressions matching tables AutoMigrate never drops
+	IgnoreTablePatterns []*regexp.Regexp
+	// ManagedTablesOnly restricts drops to tables created or renamed by a migration in the migration folder,
+	// leaving tables owned by other services, extensions or hand-written SQL alone
+	ManagedTablesOnly bool
+	// AllowDestructive lets "create" write migrations that drop tables or columns or narrow column types
+	AllowDestructive bool
+	// Confirm is asked whether to go ahead with a change that needs the user's consent, like a
+	// destructive migration when AllowDestructive is off; without it such changes fail
+	Confirm func(question string) bool
+	// ShadowDB is a scratch database AutoMigrate, "create", "diff" and "check" compare the models with
+	// instead of DB, once every migration in the migration folder is applied to it. Everything in it is
+	// dropped before and after.
+	ShadowDB *gorm.DB
+	// Offline makes AutoMigrate, "create", "diff" and "check" compare the models with the snapshot the latest
+	// migration in the migration folder wrote instead of a database, DB is only used for its dialector
+	Offline bool
+	// SQLiteVersion is the SQLite release Offline migrations are generated for, it decides whether a column
+	// is dropped in place or by rebuilding its table. Empty assumes a release that drops columns in place.
+	SQLiteVersion string
+	// PreviousTableNames lists the names a model's table had before, by its current name, for models that
+	// cannot implement PreviousTableNamer
+	PreviousTableNames map[string][]string
+	// Dialect overrides the dialect registered for the database's gorm.Dialector, for databases like
+	// CockroachDB that share a driver with another
+	Dialect Dialect
 	gorm.Dialector
 }
 
+// PreviousTableNamer is implemented by models whose table was renamed, the first of the previous tables
+// found is renamed to the model's table instead of being dropped while an empty one is created
+type PreviousTableNamer interface {
+	PreviousTableNames() []string
+}
+
 // GormDataTypeInterface gorm data type interface
 type GormDataTypeInterface interface {
 	GormDBDataType(*gorm.DB, *schema.Field) string
@@ -61,6 +103,8 @@ func New(db *gorm.DB, migrationFolder ...string) *Migrator {
 			DB:                          db,
 			NamingStrategy:              defaultNamingStrategy,
 			DownMigrationsEnabled:       true,
+			Output:                      os.Stdout,
+			Dialector:                   db.Dialector,
 		},
 		Models:        make([]interface{}, 0),
 		migrationPath: migrationPath,
@@ -89,101 +133,543 @@ func (m *Migrator) RunWithValue(value interface{}, fc func(*gorm.Statement) erro
 	return fc(stmt)
 }
 
-// AutoMigrate auto migrate values
-func (m *Migrator) AutoMigrate() (string, string, error) {
-	var migrationSQLUp string
-	var migrationSQLUpDown string
-	taps := "\n"
-	excludedTables := m.ExcludedTable(m.Models)
-	if len(excludedTables) > 0 {
-		for _, tableName := range excludedTables {
-			if tableName == "schema_migrations" {
-				continue
-			}
-			migrationSQLUpDown += "-- Drop Table \n"
-			migrationSQLUpDown += fmt.Sprintf("DROP TABLE IF EXISTS %s\n", tableName)
+// AutoMigrate auto migrate values, against the snapshot when Offline or ShadowDB when set
+func (m *Migrator) AutoMigrate() (up string, down string, err error) {
+	err = m.withTarget(func(target *Migrator) error {
+		plan, err := target.Plan()
+		if err != nil {
+			return err
+		}
+		up, down, err = target.Render(plan)
+		return err
+	})
+	return up, down, err
+}
+
+// Plan compares the registered models with the database and returns the operations that bring the
+// database in line with them, without rendering any SQL
+func (m *Migrator) Plan() (*Plan, error) {
+	d, err := m.dialect()
+	if err != nil {
+		return nil, err
+	}
+
+	renamedTables, err := m.renamedTables(d)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+	excludedTables, err := m.excludedTables(m.Models)
+	if err != nil {
+		return nil, err
+	}
+	// a renamed table is kept under its new name
+	droppedTables := excludedTables[:0:0]
+	for _, table := range excludedTables {
+		if !renamedFrom(renamedTables, table) {
+			droppedTables = append(droppedTables, table)
+		}
+	}
+	var dropTableOps []Operation
+	if len(droppedTables) > 0 {
+		if dropTableOps, err = m.planDropTables(droppedTables); err != nil {
+			return nil, err
 		}
 	}
 
+	// the constraints and indexes of the kept tables are dropped first, as they may reference a dropped
+	// table, and their columns once nothing in a dropped table references them anymore
+	var dropOps, dropColumnOps, changeOps []Operation
 	for _, value := range m.ReorderModels(m.Models, true) {
-		if !m.HasTable(value) {
-			migrationSQLUp_, migrationSQLUpDown_ := m.CreateTable(value)
-			migrationSQLUp += migrationSQLUp_ + taps
-			migrationSQLUpDown += migrationSQLUpDown_ + taps
-		} else {
-			var alterSchemaSQL string
-			var revertAlterSchemaSQL string
-			if err := m.RunWithValue(value, func(stmt *gorm.Statement) (errr error) {
-				columnTypes, err := m.ColumnTypes(value)
-				if err != nil {
-					return err
-				}
-				removedColumnMap := map[string]bool{}
-				foundColumnMap := map[string]bool{}
-				for _, dbName := range stmt.Schema.DBNames {
-					field := stmt.Schema.FieldsByDBName[dbName]
-					var foundColumn gorm.ColumnType
-
-					for _, columnType := range columnTypes {
-						columnTypeName := columnType.Name()
-						if columnTypeName == dbName {
-							foundColumn = columnType
-							break
-						}
+		if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			if from, renamed := renamedTables[stmt.Table]; renamed {
+				// the table is compared under its previous name, what is dropped goes before it is renamed
+				return m.RunWithValue(from, func(previous *gorm.Statement) error {
+					previous.Schema = stmt.Schema
+					tableDropOps, tableDropColumnOps, tableChangeOps, err := m.planTable(d, value, stmt, previous)
+					if err != nil {
+						return err
 					}
-
-					if foundColumn == nil {
-						// not found, add column
-						foundColumnMap[dbName] = true
-						alterSchemaSQL += m.AddColumn(value, dbName)
-						revertAlterSchemaSQL += m.DropColumn(stmt, dbName)
-					} else {
-						alterSchemaSQL += m.MigrateColumn(value, field, foundColumn, stmt)
-						// found, smart migrate
+					for _, op := range append(tableDropOps, tableDropColumnOps...) {
+						setTable(op, from)
 					}
+					dropOps = append(dropOps, tableDropOps...)
+					dropColumnOps = append(dropColumnOps, tableDropColumnOps...)
+					changeOps = append(changeOps, &RenameTable{Table: stmt.Table, Model: value, From: from})
+					changeOps = append(changeOps, tableChangeOps...)
+					return nil
+				})
+			}
+
+			hasTable, err := d.HasTable(m, stmt)
+			if err != nil {
+				return err
+			}
+			if !hasTable {
+				changeOps = append(changeOps, &CreateTable{Table: stmt.Table, Model: value})
+				return nil
+			}
+
+			tableDropOps, tableDropColumnOps, tableChangeOps, err := m.planTable(d, value, stmt, stmt)
+			dropOps = append(dropOps, tableDropOps...)
+			dropColumnOps = append(dropColumnOps, tableDropColumnOps...)
+			changeOps = append(changeOps, tableChangeOps...)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	plan.Operations = append(plan.Operations, dropOps...)
+	plan.Operations = append(plan.Operations, dropTableOps...)
+	plan.Operations = append(plan.Operations, dropColumnOps...)
+	plan.Operations = append(plan.Operations, changeOps...)
+	return plan, nil
+}
+
+// planTable compares the model of stmt with its table as catalog reads it, and returns the operations that
+// drop the indexes and constraints the model no longer has, those that drop its removed columns, and those
+// that change and add the rest
+func (m *Migrator) planTable(d Dialect, value interface{}, stmt, catalog *gorm.Statement) (dropOps, dropColumnOps, changeOps []Operation, err error) {
+	columnTypes, err := d.ColumnTypes(m, catalog)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	renames, err := m.planRenames(d, stmt, columnTypes)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// columns are dropped before the table's other changes, so their names can be reused
+	var alterOps []Operation
+	for _, dbName := range stmt.Schema.DBNames {
+		field := stmt.Schema.FieldsByDBName[dbName]
+		var foundColumn *ColumnType
+
+		for i := range columnTypes {
+			if columnTypes[i].Name() == dbName {
+				foundColumn = &columnTypes[i]
+				break
+			}
+		}
+
+		if from, renamed := renames[dbName]; renamed {
+			// the column keeps its data under the new name, then changes like any other
+			alterOps = append(alterOps, &RenameColumn{Table: stmt.Table, Model: value, Column: from, Field: field})
+			from.NameValue = sql.NullString{String: dbName, Valid: true}
+			foundColumn = &from
+		}
+
+		if foundColumn == nil {
+			// not found, add column
+			if !field.IgnoreMigration {
+				alterOps = append(alterOps, &AddColumn{Table: stmt.Table, Model: value, Field: field})
+			}
+		} else if changes := m.MigrateColumn(value, field, *foundColumn, stmt); changes.Any() {
+			// found, smart migrate
+			alterOps = append(alterOps, &AlterColumn{Table: stmt.Table, Model: value, Before: *foundColumn, After: field, Changes: changes})
+		}
+	}
+
+	dependents, err := m.loadTableDependents(catalog)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	dropped := map[string]bool{}
+
+	// drop the indexes that have been removed from model, and those whose definition changed so they
+	// can be recreated
+	modelIndexes := stmt.Schema.ParseIndexes()
+	changedIndexes := map[string]Index{}
+	for _, idx := range dependents.indexes {
+		if !dependents.droppableIndex(idx) {
+			continue
+		}
+		if modelIndex, declared := modelIndexes[idx.Name]; declared {
+			if len(indexChanges(idx, &modelIndex)) == 0 {
+				continue
+			}
+			changedIndexes[idx.Name] = idx
+		}
+		dropped["index:"+idx.Name] = true
+		dropOps = append(dropOps, &DropIndex{Table: stmt.Table, Index: idx})
+	}
+
+	dropForeignKeyOps, addForeignKeyOps := m.planForeignKeys(stmt, dependents, dropped)
+	dropCheckOps, addCheckOps := m.planChecks(stmt, dependents, dropped)
+	dropOps = append(append(dropForeignKeyOps, dropCheckOps...), dropOps...)
+
+	// check for column that have been removed from model and remove them in table
+	for _, columnType := range columnTypes {
+		if _, found := stmt.Schema.FieldsByDBName[columnType.Name()]; found || renamedColumn(renames, columnType.Name()) {
+			continue
+		}
+		dropColumnOps = append(dropColumnOps, planDropColumn(stmt.Table, columnType, dependents, dropped)...)
+	}
+
+	for _, name := range sortedIndexNames(modelIndexes) {
+		idx := modelIndexes[name]
+		if live, changed := changedIndexes[name]; changed {
+			alterOps = append(alterOps, &CreateIndex{Table: stmt.Table, Model: value, Index: &idx, Replaces: &live})
+		} else if _, found := lookupIndex(dependents.indexes, name); !found {
+			alterOps = append(alterOps, &CreateIndex{Table: stmt.Table, Model: value, Index: &idx})
+		}
+	}
 
+	changeOps = append(changeOps, alterOps...)
+	changeOps = append(changeOps, addCheckOps...)
+	changeOps = append(changeOps, addForeignKeyOps...)
+	return dropOps, dropColumnOps, changeOps, nil
+}
+
+// tableDependents holds the catalog objects of a table that can reference its columns
+type tableDependents struct {
+	dialect     Dialect
+	indexes     []Index
+	foreignKeys []ForeignKey
+	checks      []Check
+}
+
+func (m *Migrator) loadTableDependents(stmt *gorm.Statement) (_ *tableDependents, err error) {
+	dependents := &tableDependents{}
+	if dependents.dialect, err = m.dialect(); err != nil {
+		return nil, err
+	}
+	if dependents.indexes, err = dependents.dialect.Indexes(m, stmt); err != nil {
+		return nil, err
+	}
+	if dependents.foreignKeys, err = dependents.dialect.ForeignKeys(m, stmt); err != nil {
+		return nil, err
+	}
+	if dependents.checks, err = dependents.dialect.Checks(m, stmt); err != nil {
+		return nil, err
+	}
+	return dependents, nil
+}
+
+// sortedIndexNames returns the names of indexes in order, so migrations come out the same every time
+func sortedIndexNames(indexes map[string]schema.Index) []string {
+	names := make([]string, 0, len(indexes))
+	for name := range indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// droppableIndex reports whether idx was created on its own rather than backing the primary key or a
+// constraint, like the index MySQL creates for a foreign key
+func (dependents *tableDependents) droppableIndex(idx Index) bool {
+	if idx.Primary || idx.Constraint {
+		return false
+	}
+	for _, fk := range dependents.foreignKeys {
+		if fk.Name == idx.Name {
+			return false
+		}
+	}
+	return true
+}
+
+// planDropColumn returns the operations that drop column along with the indexes and constraints referencing
+// it, the constraints first. dropped records the dependents already dropped for another column.
+func planDropColumn(table string, column ColumnType, dependents *tableDependents, dropped map[string]bool) []Operation {
+	var dropConstraintOps, dropIndexOps []Operation
+	name := column.Name()
+
+	for _, fk := range dependents.foreignKeys {
+		if fk.References(name) && !dropped["fk:"+fk.Name] {
+			dropped["fk:"+fk.Name] = true
+			dropConstraintOps = append(dropConstraintOps, &DropForeignKey{Table: table, ForeignKey: fk})
+		}
+	}
+	for _, chk := range dependents.checks {
+		if chk.References(name) && !dropped["check:"+chk.Name] {
+			dropped["check:"+chk.Name] = true
+			dropConstraintOps = append(dropConstraintOps, &DropCheck{Table: table, Check: chk})
+		}
+	}
+	// indexes backing constraints go with the constraint, the column's own UNIQUE is restored with it
+	for _, idx := range dependents.indexes {
+		if idx.References(name) && !idx.Constraint && !dropped["index:"+idx.Name] {
+			dropped["index:"+idx.Name] = true
+			dropIndexOps = append(dropIndexOps, &DropIndex{Table: table, Index: idx})
+		}
+	}
+
+	ops := append(dropConstraintOps, dropIndexOps...)
+	return append(ops, &DropColumn{Table: table, Column: column})
+}
+
+// renamedTables returns the previous name of each model table that is missing while a table it had
+// before exists, by the model's table. A table another model maps to is never taken.
+func (m *Migrator) renamedTables(d Dialect) (map[string]string, error) {
+	values := m.ReorderModels(m.Models, true)
+	modelTables := map[string]bool{}
+	for _, value := range values {
+		if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			modelTables[stmt.Table] = true
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	renamed := map[string]string{}
+	for _, value := range values {
+		if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			previousNames := m.PreviousTableNames[stmt.Table]
+			if namer, ok := value.(PreviousTableNamer); ok {
+				previousNames = append(namer.PreviousTableNames(), previousNames...)
+			}
+			if len(previousNames) == 0 {
+				return nil
+			}
+			if hasTable, err := d.HasTable(m, stmt); err != nil || hasTable {
+				return err
+			}
+
+			for _, previous := range previousNames {
+				if modelTables[previous] || renamedFrom(renamed, previous) {
+					continue
 				}
-				// check for column that have been removed from model and remove them in table
-				for _, columnType := range columnTypes {
-					columnTypeName := columnType.Name()
-					_, foundInFieldsByDBName := stmt.Schema.FieldsByDBName[columnTypeName]
-					_, removedColumn := removedColumnMap[columnTypeName]
-					if !foundInFieldsByDBName && !removedColumn {
-						migrationSQLUp += m.DropColumn(stmt, columnTypeName)
-						// TODO: add the sql type
-						migrationSQLUpDown += buildRawSQL(m.DB, "ALTER TABLE ? ADD ?", []interface{}{m.CurrentTable(stmt), clause.Column{Name: columnTypeName}}...)
-						// make it has removed
-						removedColumnMap[columnTypeName] = true
-					}
+				var found bool
+				if err := m.RunWithValue(previous, func(previousStmt *gorm.Statement) (err error) {
+					found, err = d.HasTable(m, previousStmt)
+					return err
+				}); err != nil {
+					return err
+				}
+				if found {
+					renamed[stmt.Table] = previous
+					return nil
 				}
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return renamed, nil
+}
 
-				for _, idx := range stmt.Schema.ParseIndexes() {
-					if !m.HasIndex(value, idx.Name) {
-						createIndexSQLRaw_, downIndexSQLRaw_ := m.CreateIndex(value, idx.Name)
-						alterSchemaSQL += createIndexSQLRaw_
-						if _, found := foundColumnMap[idx.Fields[0].DBName]; !found {
-							revertAlterSchemaSQL += downIndexSQLRaw_
-						}
-					}
+// renamedFrom reports whether the table called name is renamed to a model's table
+func renamedFrom(renamed map[string]string, name string) bool {
+	for _, from := range renamed {
+		if from == name {
+			return true
+		}
+	}
+	return false
+}
+
+// setTable points a drop operation at table, for those that run before their table is renamed
+func setTable(op Operation, table string) {
+	switch op := op.(type) {
+	case *DropColumn:
+		op.Table = table
+	case *DropIndex:
+		op.Table = table
+	case *DropForeignKey:
+		op.Table = table
+	case *DropCheck:
+		op.Table = table
+	}
+}
+
+// planRenames returns the columns of stmt's table renamed to a model field, by the field's DBName. A field
+// tagged `migrator:"renamed_from:old_name"` takes over old_name, and a dropped column sharing its type with an
+// added one is only taken for a rename once confirmed; without Confirm it fails with a *PossibleRenameError
+// unless AllowDestructive lets the column be dropped and the new one added.
+func (m *Migrator) planRenames(d Dialect, stmt *gorm.Statement, columnTypes []ColumnType) (map[string]ColumnType, error) {
+	var added []*schema.Field
+	for _, dbName := range stmt.Schema.DBNames {
+		field := stmt.Schema.FieldsByDBName[dbName]
+		if _, found := lookupColumn(columnTypes, dbName); !found && !field.IgnoreMigration {
+			added = append(added, field)
+		}
+	}
+	var removed []ColumnType
+	for _, columnType := range columnTypes {
+		if _, found := stmt.Schema.FieldsByDBName[columnType.Name()]; !found {
+			removed = append(removed, columnType)
+		}
+	}
+
+	renames := map[string]ColumnType{}
+	for _, field := range added {
+		from := schema.ParseTagSetting(field.Tag.Get("migrator"), ";")["RENAMED_FROM"]
+		for i, column := range removed {
+			if from != "" && column.Name() == from {
+				renames[field.DBName] = column
+				removed = append(removed[:i], removed[i+1:]...)
+				break
+			}
+		}
+	}
+
+	for _, column := range removed {
+		for _, field := range added {
+			if _, taken := renames[field.DBName]; taken || d.NormalizeType(m.DataTypeOf(field)) != d.NormalizeType(column.DatabaseTypeName()) {
+				continue
+			}
+			if m.Confirm == nil {
+				if m.AllowDestructive {
+					break
 				}
+				return nil, &PossibleRenameError{Table: stmt.Table, From: column.Name(), To: field.DBName}
+			}
+			question := fmt.Sprintf("Was column %s.%s renamed to %s? Otherwise it is dropped and %s added.", stmt.Table, column.Name(), field.DBName, field.DBName)
+			if m.Confirm(question) {
+				renames[field.DBName] = column
+				break
+			}
+		}
+	}
+	return renames, nil
+}
 
-				return nil
-			}); err != nil {
-				return "", "", err
+// lookupColumn returns the column called name
+func lookupColumn(columnTypes []ColumnType, name string) (ColumnType, bool) {
+	for _, columnType := range columnTypes {
+		if columnType.Name() == name {
+			return columnType, true
+		}
+	}
+	return ColumnType{}, false
+}
+
+// renamedColumn reports whether the column called name is renamed to a model field
+func renamedColumn(renames map[string]ColumnType, name string) bool {
+	for _, from := range renames {
+		if from.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// planDropTables returns the operations that drop tables, each before the tables it references,
+// with the catalog metadata that recreates them
+func (m *Migrator) planDropTables(tables []string) ([]Operation, error) {
+	d, err := m.dialect()
+	if err != nil {
+		return nil, err
+	}
+
+	dropped := map[string]*DropTable{}
+	for _, table := range tables {
+		if err := m.RunWithValue(table, func(stmt *gorm.Statement) (err error) {
+			op := &DropTable{Table: table}
+			if op.Columns, err = d.ColumnTypes(m, stmt); err != nil {
+				return err
+			}
+			dependents, err := m.loadTableDependents(stmt)
+			if err != nil {
+				return err
+			}
+			op.Indexes, op.ForeignKeys, op.Checks = dependents.indexes, dependents.foreignKeys, dependents.checks
+			dropped[table] = op
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		ordered []Operation
+		visited = map[string]bool{}
+		visit   func(table string)
+	)
+	// a table is visited after the tables it references, so it is dropped before them
+	visit = func(table string) {
+		if visited[table] {
+			return
+		}
+		visited[table] = true
+		for _, fk := range dropped[table].ForeignKeys {
+			if _, ok := dropped[fk.ReferencedTable]; ok {
+				visit(fk.ReferencedTable)
 			}
+		}
+		ordered = append([]Operation{dropped[table]}, ordered...)
+	}
+	for _, table := range tables {
+		if _, ok := dropped[table]; ok {
+			visit(table)
+		}
+	}
+	return ordered, nil
+}
+
+// createTableFromCatalog returns the statements that recreate stmt's table with the columns, primary key,
+// indexes and constraints the catalog describes
+func (m *Migrator) createTableFromCatalog(stmt *gorm.Statement, columns []ColumnType, dependents *tableDependents) string {
+	createTableSQL, dependentsSQL := m.catalogTableSQL(stmt, columns, dependents)
+	return "-- Create Table \n" + createTableSQL + dependentsSQL
+}
 
-			migrationSQLUp += alterSchemaSQL + taps
-			migrationSQLUpDown += revertAlterSchemaSQL + taps
+// catalogTableSQL renders the CREATE TABLE statement of a table described by the catalog, and separately the
+// statements that create its indexes and comments
+func (m *Migrator) catalogTableSQL(stmt *gorm.Statement, columns []ColumnType, dependents *tableDependents) (string, string) {
+	var (
+		d              = dependents.dialect
+		createTableSQL = "CREATE TABLE ? ("
+		values         = []interface{}{m.CurrentTable(stmt)}
+		primaryKeys    []string
+		uniqueColumns  = map[string]bool{}
+		createIndexSQL string
+		commentSQL     string
+	)
+
+	for _, column := range columns {
+		createTableSQL += "? ?,"
+		values = append(values, clause.Column{Name: column.Name()}, clause.Expr{SQL: d.ColumnDefinition(m, column)})
+		if isPrimaryKey, _ := column.PrimaryKey(); isPrimaryKey {
+			primaryKeys = append(primaryKeys, column.Name())
+		}
+		if unique, _ := column.Unique(); unique {
+			uniqueColumns[column.Name()] = true
+		}
+		commentSQL += d.ColumnCommentSQL(m, stmt, column)
+	}
+
+	for _, idx := range dependents.indexes {
+		switch {
+		case idx.Primary:
+			// the index knows the primary key's column order
+			primaryKeys = idx.Columns
+		case idx.Constraint:
+			if len(idx.Columns) == 1 && uniqueColumns[idx.Columns[0]] {
+				continue
+			}
+			createTableSQL += "CONSTRAINT ? UNIQUE ?,"
+			values = append(values, clause.Column{Name: idx.Name}, columnList(idx.Columns))
+		default:
+			createIndexSQL += idx.Definition + "; \n"
 		}
 	}
 
-	if strings.TrimSpace(migrationSQLUp) != "" || strings.TrimSpace(migrationSQLUpDown) != "" {
-		migrationSQLUp = "BEGIN;\n\n" + migrationSQLUp + "\nCOMMIT;"
-		migrationSQLUpDown = "BEGIN;\n\n" + migrationSQLUpDown + "\nCOMMIT;"
-		return migrationSQLUp, migrationSQLUpDown, nil
+	if len(primaryKeys) > 0 {
+		createTableSQL += "PRIMARY KEY ?,"
+		values = append(values, columnList(primaryKeys))
+	}
+
+	for _, fk := range dependents.foreignKeys {
+		sql, vars := foreignKeyClause(fk)
+		createTableSQL += sql + ","
+		values = append(values, vars...)
+	}
+
+	for _, chk := range dependents.checks {
+		createTableSQL += "CONSTRAINT ? CHECK (?),"
+		values = append(values, clause.Column{Name: chk.Name}, clause.Expr{SQL: chk.Expression})
 	}
 
-	return "", "", nil
+	createTableSQL = strings.TrimSuffix(createTableSQL, ",") + ")"
+	return buildRawSQL(m.DB, createTableSQL, values...), createIndexSQL + commentSQL
 }
 
 // CreateTable create table in database for values
@@ -291,48 +777,84 @@ func (m *Migrator) CreateTable(values ...interface{}) (string, string) {
 
 // HasTable returns table exists or not for value, value could be a struct or string
 func (m *Migrator) HasTable(value interface{}) bool {
-	var count int64
-	m.RunWithValue(value, func(stmt *gorm.Statement) error {
-		currentSchema, curTable := m.CurrentSchema(stmt, stmt.Table)
-		return m.DB.Raw("SELECT count(*) FROM information_schema.tables WHERE table_schema = ? AND table_name = ? AND table_type = ?", currentSchema, curTable, "BASE TABLE").Scan(&count).Error
+	has, _ := m.hasTable(value)
+	return has
+}
+
+func (m *Migrator) hasTable(value interface{}) (has bool, err error) {
+	d, err := m.dialect()
+	if err != nil {
+		return false, err
+	}
+	err = m.RunWithValue(value, func(stmt *gorm.Statement) (err error) {
+		has, err = d.HasTable(m, stmt)
+		return err
 	})
-	return count > 0
+	return has, err
 }
 
-// ExcludedTable returns list of excluded tables in the database.
+// ExcludedTable returns the tables in the database that are not registered and may be dropped,
+// leaving out ignored and, with ManagedTablesOnly, unmanaged tables.
 func (m *Migrator) ExcludedTable(values []interface{}) []string {
-	type Tables struct {
-		TableName string
+	excludedTables, _ := m.excludedTables(values)
+	return excludedTables
+}
+
+func (m *Migrator) excludedTables(values []interface{}) ([]string, error) {
+	d, err := m.dialect()
+	if err != nil {
+		return nil, err
 	}
 
-	var currentTables []Tables
-	var excludedTables []string
-	m.RunWithValue(values[0], func(stmt *gorm.Statement) error {
-		currentSchema, _ := m.CurrentSchema(stmt, stmt.Table)
-		return m.DB.Raw("SELECT table_name FROM information_schema.tables WHERE table_schema = ? AND table_type = ?", currentSchema, "BASE TABLE").Scan(&currentTables).Error
-	})
+	var currentTables []string
+	stmt := &gorm.Statement{DB: m.DB}
+	if len(values) > 0 {
+		err = m.RunWithValue(values[0], func(s *gorm.Statement) (err error) {
+			currentTables, err = d.Tables(m, s)
+			return err
+		})
+	} else {
+		currentTables, err = d.Tables(m, stmt)
+	}
+	if err != nil {
+		return nil, err
+	}
 
+	// dependencies ReorderModels adds, like many2many join tables, are managed too
+	values = m.ReorderModels(values, true)
+
+	var excludedTables []string
 	tableName := map[string]bool{}
-	stmt := &gorm.Statement{DB: m.DB}
 	for i := len(values) - 1; i >= 0; i-- {
 		if table, ok := values[i].(string); ok {
 			tableName[table] = true
 		} else {
 			if err := stmt.ParseWithSpecialTableName(values[i], ""); err != nil {
-				return nil
+				return nil, err
 			}
 			tableName[stmt.Table] = true
 			stmt.Table = ""
 		}
 	}
 
-	for _, val := range currentTables {
-		if _, ok := tableName[val.TableName]; !ok {
-			excludedTables = append(excludedTables, val.TableName)
+	var managedTables map[string]bool
+	if m.ManagedTablesOnly {
+		if managedTables, err = m.managedTables(); err != nil {
+			return nil, err
 		}
 	}
 
-	return excludedTables
+	for _, table := range currentTables {
+		if _, ok := tableName[table]; ok || m.ignoredTable(table) {
+			continue
+		}
+		if m.ManagedTablesOnly && !managedTables[table] {
+			continue
+		}
+		excludedTables = append(excludedTables, table)
+	}
+
+	return excludedTables, nil
 }
 
 func (m *Migrator) CurrentSchema(stmt *gorm.Statement, table string) (interface{}, interface{}) {
@@ -371,23 +893,30 @@ func (m *Migrator) AddColumn(value interface{}, name string) string {
 
 // DropColumn drop value's `name` column
 func (m *Migrator) DropColumn(stmt *gorm.Statement, name string) string {
-	var dropColumnRawSQL string
-
-	if field := stmt.Schema.LookUpField(name); field != nil {
-		name = field.DBName
+	if stmt.Schema != nil {
+		if field := stmt.Schema.LookUpField(name); field != nil {
+			name = field.DBName
+		}
 	}
-	dropColumnRawSQL = buildRawSQL(m.DB, "ALTER TABLE ? DROP COLUMN ?", []interface{}{m.CurrentTable(stmt), clause.Column{Name: name}}...)
 
-	return dropColumnRawSQL
+	d, err := m.dialect()
+	if err != nil {
+		return dropColumnSQL(m, stmt, name)
+	}
+	return d.DropColumnSQL(m, stmt, name)
 }
 
 // AlterColumn alter value's `field` column' type based on schema definition
 func (m *Migrator) AlterColumn(value interface{}, field string) string {
 	var alterColumnRawSQL string
+	d, err := m.dialect()
+	if err != nil {
+		return ""
+	}
 	m.RunWithValue(value, func(stmt *gorm.Statement) error {
 		if field := stmt.Schema.LookUpField(field); field != nil {
-			fileType := m.DB.Migrator().FullDataTypeOf(field)
-			alterColumnRawSQL = buildRawSQL(m.DB, "ALTER TABLE ? ALTER COLUMN ? TYPE ?", []interface{}{m.CurrentTable(stmt), clause.Column{Name: field.DBName}, fileType}...)
+			alterColumnRawSQL = d.AlterColumnSQL(m, stmt, m.columnTypeOf(field), ColumnChanges{Type: true})
+			return nil
 		}
 		return fmt.Errorf("failed to look up field with name: %s", field)
 	})
@@ -396,35 +925,44 @@ func (m *Migrator) AlterColumn(value interface{}, field string) string {
 
 // HasColumn check has column `field` for value or not
 func (m *Migrator) HasColumn(value interface{}, field string) bool {
-	var count int64
+	var has bool
 	m.RunWithValue(value, func(stmt *gorm.Statement) error {
-		currentDatabase := m.DB.Migrator().CurrentDatabase()
 		name := field
 		if field := stmt.Schema.LookUpField(field); field != nil {
 			name = field.DBName
 		}
 
-		return m.DB.Raw(
-			"SELECT count(*) FROM INFORMATION_SCHEMA.columns WHERE table_schema = ? AND table_name = ? AND column_name = ?",
-			currentDatabase, stmt.Table, name,
-		).Row().Scan(&count)
+		columnTypes, err := m.ColumnTypes(value)
+		for _, columnType := range columnTypes {
+			if columnType.Name() == name {
+				has = true
+			}
+		}
+		return err
 	})
 
-	return count > 0
+	return has
 }
 
-// MigrateColumn migrate column
-func (m *Migrator) MigrateColumn(value interface{}, field *schema.Field, columnType gorm.ColumnType, stmt *gorm.Statement) string {
+// MigrateColumn reports which attributes of columnType differ from field
+func (m *Migrator) MigrateColumn(value interface{}, field *schema.Field, columnType gorm.ColumnType, stmt *gorm.Statement) (changes ColumnChanges) {
+	if field.IgnoreMigration {
+		return changes
+	}
+
 	// found, smart migrate
 	fullDataType := strings.ToLower(m.DB.Migrator().FullDataTypeOf(field).SQL)
 	realDataType := strings.ToLower(columnType.DatabaseTypeName())
 
-	alterColumn := false
+	// check type, by the name the database knows it by
+	if d, err := m.dialect(); err == nil && d.NormalizeType(m.DataTypeOf(field)) != d.NormalizeType(columnType.DatabaseTypeName()) {
+		changes.Type = true
+	}
 
 	// check size
 	if length, ok := columnType.Length(); length != int64(field.Size) {
 		if length > 0 && field.Size > 0 {
-			alterColumn = true
+			changes.Type = true
 		} else {
 			// has size in data type and not equal
 			// Since the following code is frequently called in the for loop, reg optimization is needed here
@@ -432,7 +970,7 @@ func (m *Migrator) MigrateColumn(value interface{}, field *schema.Field, columnT
 			matches2 := regFullDataType.FindAllStringSubmatch(fullDataType, -1)
 			if (len(matches) == 1 && matches[0][1] != fmt.Sprint(field.Size) || !field.PrimaryKey) &&
 				(len(matches2) == 1 && matches2[0][1] != fmt.Sprint(length) && ok) {
-				alterColumn = true
+				changes.Type = true
 			}
 		}
 	}
@@ -440,15 +978,15 @@ func (m *Migrator) MigrateColumn(value interface{}, field *schema.Field, columnT
 	// check precision
 	if precision, _, ok := columnType.DecimalSize(); ok && int64(field.Precision) != precision {
 		if regexp.MustCompile(fmt.Sprintf("[^0-9]%d[^0-9]", field.Precision)).MatchString(m.DataTypeOf(field)) {
-			alterColumn = true
+			changes.Type = true
 		}
 	}
 
 	// check nullable
 	if nullable, ok := columnType.Nullable(); ok && nullable == field.NotNull {
-		// not primary key & database is nullable
-		if !field.PrimaryKey && nullable {
-			alterColumn = true
+		// not primary key
+		if !field.PrimaryKey {
+			changes.Nullable = true
 		}
 	}
 
@@ -456,15 +994,15 @@ func (m *Migrator) MigrateColumn(value interface{}, field *schema.Field, columnT
 	if unique, ok := columnType.Unique(); ok && unique != field.Unique {
 		// not primary key
 		if !field.PrimaryKey {
-			alterColumn = true
+			changes.Unique = true
 		}
 	}
 
 	// check default value
-	if v, ok := columnType.DefaultValue(); ok && v != field.DefaultValue {
+	if v, ok := columnType.DefaultValue(); (ok && !strings.EqualFold(v, "null") && v != field.DefaultValue) || (!ok && fieldDefault(field) != "") {
 		// not primary key
 		if !field.PrimaryKey {
-			alterColumn = true
+			changes.Default = true
 		}
 	}
 
@@ -472,42 +1010,24 @@ func (m *Migrator) MigrateColumn(value interface{}, field *schema.Field, columnT
 	if comment, ok := columnType.Comment(); ok && comment != field.Comment {
 		// not primary key
 		if !field.PrimaryKey {
-			alterColumn = true
+			changes.Comment = true
 		}
 	}
 
-	if alterColumn && !field.IgnoreMigration {
-		return m.AlterColumn(value, field.Name)
-	}
-
-	return ""
+	return changes
 }
 
-// ColumnTypes return columnTypes []gorm.ColumnType and execErr error
-// TODO: rewrite this function
+// ColumnTypes returns the columns of value's table read from the database catalog, without reading any rows
 func (m *Migrator) ColumnTypes(value interface{}) ([]ColumnType, error) {
-	columnTypes := make([]ColumnType, 0)
-	execErr := m.RunWithValue(value, func(stmt *gorm.Statement) (err error) {
-		rows, err := m.DB.Session(&gorm.Session{}).Table(stmt.Table).Rows()
-		if err != nil {
-			return err
-		}
-
-		defer func() {
-			err = rows.Close()
-		}()
-
-		var rawColumnTypes []*sql.ColumnType
-		rawColumnTypes, err = rows.ColumnTypes()
-		if err != nil {
-			return err
-		}
-
-		for _, c := range rawColumnTypes {
-			columnTypes = append(columnTypes, ColumnType{SQLColumnType: c})
-		}
+	d, err := m.dialect()
+	if err != nil {
+		return nil, err
+	}
 
-		return
+	var columnTypes []ColumnType
+	execErr := m.RunWithValue(value, func(stmt *gorm.Statement) (err error) {
+		columnTypes, err = d.ColumnTypes(m, stmt)
+		return err
 	})
 
 	return columnTypes, execErr
@@ -621,32 +1141,14 @@ type BuildIndexOptionsInterface interface {
 func (m *Migrator) CreateIndex(value interface{}, name string) (string, string) {
 	var createIndexRawSQL string
 	var dropIndexRawSQL string
+	d, err := m.dialect()
+	if err != nil {
+		return "", ""
+	}
 	m.RunWithValue(value, func(stmt *gorm.Statement) error {
 		if idx := stmt.Schema.LookIndex(name); idx != nil {
-			opts := m.BuildIndexOptions(idx.Fields, stmt)
-			values := []interface{}{clause.Column{Name: idx.Name}, m.CurrentTable(stmt), opts}
-
-			createIndexSQL := "CREATE "
-			dropIndexSQL := "DROP INDEX ?"
-			if idx.Class != "" {
-				createIndexSQL += idx.Class + " "
-			}
-			createIndexSQL += "INDEX ? ON ??"
-
-			if idx.Type != "" {
-				createIndexSQL += " USING " + idx.Type
-			}
-
-			if idx.Comment != "" {
-				createIndexSQL += fmt.Sprintf(" COMMENT '%s'", idx.Comment)
-			}
-
-			if idx.Option != "" {
-				createIndexSQL += " " + idx.Option
-			}
-
-			createIndexRawSQL = buildRawSQL(m.DB, createIndexSQL, values...)
-			dropIndexRawSQL = buildRawSQL(m.DB, dropIndexSQL, values...)
+			createIndexRawSQL = d.CreateIndexSQL(m, stmt, idx)
+			dropIndexRawSQL = d.DropIndexSQL(m, stmt, Index{Name: idx.Name, Table: stmt.Table})
 		}
 		return nil
 	})
@@ -655,23 +1157,32 @@ func (m *Migrator) CreateIndex(value interface{}, name string) (string, string)
 
 // HasIndex check has index `name` or not
 func (m *Migrator) HasIndex(value interface{}, name string) bool {
-	var count int64
-	m.RunWithValue(value, func(stmt *gorm.Statement) error {
-		if idx := stmt.Schema.LookIndex(name); idx != nil {
-			name = idx.Name
+	has, _ := m.hasIndex(value, name)
+	return has
+}
+
+func (m *Migrator) hasIndex(value interface{}, name string) (has bool, err error) {
+	d, err := m.dialect()
+	if err != nil {
+		return false, err
+	}
+	err = m.RunWithValue(value, func(stmt *gorm.Statement) (err error) {
+		if stmt.Schema != nil {
+			if idx := stmt.Schema.LookIndex(name); idx != nil {
+				name = idx.Name
+			}
 		}
-		currentSchema, curTable := m.CurrentSchema(stmt, stmt.Table)
-		return m.DB.Raw(
-			"SELECT count(*) FROM pg_indexes WHERE tablename = ? AND indexname = ? AND schemaname = ?", curTable, name, currentSchema,
-		).Scan(&count).Error
+		has, err = d.HasIndex(m, stmt, name)
+		return err
 	})
-
-	return count > 0
+	return has, err
 }
 
 // CurrentDatabase returns current database name
 func (m *Migrator) CurrentDatabase() (name string) {
-	m.DB.Raw("SELECT DATABASE()").Row().Scan(&name)
+	if d, err := m.dialect(); err == nil {
+		name, _ = d.CurrentDatabase(m)
+	}
 	return
 }
 
@@ -0,0 +1,143 @@
+package migrator
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openSQLite opens a SQLite database in a temporary file, golang-migrate needs a file to open again
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("error opening sqlite: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// openIntegration opens the database the environment variable env holds the DSN of, and skips the test
+// when it is unset. Everything in the database is dropped before and after the test.
+func openIntegration(t *testing.T, env string, open func(dsn string) gorm.Dialector) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv(env)
+	if dsn == "" {
+		t.Skipf("%s is not set", env)
+	}
+	db, err := gorm.Open(open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("error opening %s: %v", env, err)
+	}
+
+	folder := t.TempDir()
+	reset := func() {
+		if err := New(db, folder).resetShadow(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reset()
+	t.Cleanup(func() {
+		reset()
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// testMigrator returns a migrator for db with models registered, writing its migrations to a temporary
+// folder. Migrations are numbered in order rather than by timestamp, so several can be created within a
+// second, and destructive changes are allowed.
+func testMigrator(t *testing.T, db *gorm.DB, models ...interface{}) *Migrator {
+	t.Helper()
+	m := New(db, t.TempDir())
+	m.Output = io.Discard
+	m.AllowDestructive = true
+	var version int
+	m.NamingStrategy = func(migrationPath, name string, _ time.Time) (string, string) {
+		version++
+		base := filepath.Join(migrationPath, fmt.Sprintf("%d_%s.", version, name))
+		return base + "up.sql", base + "down.sql"
+	}
+	m.RegisterModel(models...)
+	return m
+}
+
+// migrateTo registers models on m in place of the previous ones, then creates a migration and applies it
+func migrateTo(t *testing.T, m *Migrator, models ...interface{}) {
+	t.Helper()
+	m.Models = nil
+	m.RegisterModel(models...)
+	mustRun(t, m, "create", "test")
+	mustRun(t, m, "up")
+}
+
+// mustRun runs command on m's database and fails the test on error
+func mustRun(t *testing.T, m *Migrator, command string, args ...string) {
+	t.Helper()
+	if err := m.Run(m.DB, command, args...); err != nil {
+		t.Fatalf("%s: %v", command, err)
+	}
+}
+
+// mustExec executes sql on db and fails the test on error
+func mustExec(t *testing.T, db *gorm.DB, sql string) {
+	t.Helper()
+	if err := db.Exec(sql).Error; err != nil {
+		t.Fatalf("error executing %q: %v", sql, err)
+	}
+}
+
+// mustPlan returns the plan of m and fails the test on error
+func mustPlan(t *testing.T, m *Migrator) *Plan {
+	t.Helper()
+	plan, err := m.Plan()
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	return plan
+}
+
+// assertNoDrift fails the test unless the database of m matches its models
+func assertNoDrift(t *testing.T, m *Migrator) {
+	t.Helper()
+	if err := m.Check(); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+}
+
+// changeIndex returns the position of the first operation of plan whose change starts with prefix, -1 when
+// there is none
+func changeIndex(plan *Plan, prefix string) int {
+	for i, change := range plan.Changes() {
+		if strings.HasPrefix(change.String(), prefix) {
+			return i
+		}
+	}
+	return -1
+}
+
+// assertChanges fails the test unless the changes of plan are want, in order
+func assertChanges(t *testing.T, plan *Plan, want ...string) {
+	t.Helper()
+	var got []string
+	for _, change := range plan.Changes() {
+		got = append(got, change.String())
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("changes:\n\t%s\nwant:\n\t%s", strings.Join(got, "\n\t"), strings.Join(want, "\n\t"))
+	}
+}
@@ -0,0 +1,216 @@
+package migrator
+
+import (
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+// Plan is the ordered list of operations that bring the database in line with the registered models
+type Plan struct {
+	Operations []Operation
+}
+
+// Operation is a single schema change in a Plan, its fields describe the schema before and after it
+type Operation interface {
+	// Change describes the operation and how it affects existing data
+	Change() Change
+}
+
+// Empty reports whether the plan has nothing to do
+func (p *Plan) Empty() bool {
+	return p == nil || len(p.Operations) == 0
+}
+
+// Changes describes every operation of the plan in order
+func (p *Plan) Changes() []Change {
+	if p == nil {
+		return nil
+	}
+	changes := make([]Change, 0, len(p.Operations))
+	for _, op := range p.Operations {
+		changes = append(changes, op.Change())
+	}
+	return changes
+}
+
+// CreateTable creates the table of a registered model, along with its indexes and constraints
+type CreateTable struct {
+	Table string
+	// Model is the registered model the table is created from
+	Model interface{}
+}
+
+// DropTable drops a table no registered model maps to
+type DropTable struct {
+	Table string
+	// Columns, Indexes, ForeignKeys and Checks describe the table before it is dropped
+	Columns     []ColumnType
+	Indexes     []Index
+	ForeignKeys []ForeignKey
+	Checks      []Check
+}
+
+// RenameTable renames the table a model was mapped to before to the model's table, the operations
+// before it in the plan apply to the table under its previous name
+type RenameTable struct {
+	Table string
+	Model interface{}
+	// From is the table's previous name
+	From string
+}
+
+// AddColumn adds the column of a model field to an existing table
+type AddColumn struct {
+	Table string
+	Model interface{}
+	Field *schema.Field
+}
+
+// DropColumn drops a column no model field maps to
+type DropColumn struct {
+	Table string
+	// Column describes the column before it is dropped
+	Column ColumnType
+}
+
+// RenameColumn renames a column to the name of the model field it was renamed to, keeping its data
+type RenameColumn struct {
+	Table string
+	Model interface{}
+	// Column describes the column before it is renamed
+	Column ColumnType
+	Field  *schema.Field
+}
+
+// AlterColumn changes a column to match its model field
+type AlterColumn struct {
+	Table string
+	Model interface{}
+	// Before is the column as the catalog described it, the down migration restores it
+	Before ColumnType
+	After  *schema.Field
+	// Changes lists the attributes that differ
+	Changes ColumnChanges
+}
+
+// CreateIndex creates an index of a model on an existing table
+type CreateIndex struct {
+	Table string
+	Model interface{}
+	Index *schema.Index
+	// Replaces describes the index of the same name a DropIndex earlier in the plan removed because
+	// its definition changed
+	Replaces *Index
+}
+
+// DropIndex drops an index
+type DropIndex struct {
+	Table string
+	// Index describes the index before it is dropped
+	Index Index
+}
+
+// AddForeignKey adds a foreign key to an existing table
+type AddForeignKey struct {
+	Table      string
+	ForeignKey ForeignKey
+	// Replaces describes the foreign key a DropForeignKey earlier in the plan removed because its
+	// definition changed
+	Replaces *ForeignKey
+}
+
+// DropForeignKey drops a foreign key
+type DropForeignKey struct {
+	Table string
+	// ForeignKey describes the foreign key before it is dropped
+	ForeignKey ForeignKey
+}
+
+// AddCheck adds a check constraint to an existing table
+type AddCheck struct {
+	Table string
+	Check Check
+	// Replaces describes the check constraint a DropCheck earlier in the plan removed because its
+	// expression changed
+	Replaces *Check
+}
+
+// DropCheck drops a check constraint
+type DropCheck struct {
+	Table string
+	// Check describes the check constraint before it is dropped
+	Check Check
+}
+
+func (op *CreateTable) Change() Change {
+	return Change{Severity: Safe, Action: "create table", Table: op.Table}
+}
+
+func (op *DropTable) Change() Change {
+	return Change{Severity: Destructive, Action: "drop table", Table: op.Table}
+}
+
+func (op *RenameTable) Change() Change {
+	return Change{Severity: Safe, Action: "rename table", Table: op.Table, Detail: "from " + op.From}
+}
+
+func (op *AddColumn) Change() Change {
+	return Change{Severity: addColumnSeverity(op.Field), Action: "add column", Table: op.Table, Column: op.Field.DBName}
+}
+
+func (op *DropColumn) Change() Change {
+	return Change{Severity: Destructive, Action: "drop column", Table: op.Table, Column: op.Column.Name()}
+}
+
+func (op *RenameColumn) Change() Change {
+	return Change{Severity: Safe, Action: "rename column", Table: op.Table, Column: op.Field.DBName, Detail: "from " + op.Column.Name()}
+}
+
+func (op *AlterColumn) Change() Change {
+	severity, reason := alterColumnSeverity(op.After, op.Before, op.Changes)
+	detail := op.Changes.String()
+	if reason != "" {
+		detail += ": " + reason
+	}
+	return Change{Severity: severity, Action: "alter column", Table: op.Table, Column: op.After.DBName, Detail: detail}
+}
+
+func (op *CreateIndex) Change() Change {
+	// building an index locks writes to the table on most databases
+	if op.Replaces != nil {
+		detail := op.Index.Name + ": " + strings.Join(indexChanges(*op.Replaces, op.Index), ", ")
+		return Change{Severity: Blocking, Action: "recreate index", Table: op.Table, Detail: detail}
+	}
+	return Change{Severity: Blocking, Action: "create index", Table: op.Table, Detail: op.Index.Name}
+}
+
+func (op *DropIndex) Change() Change {
+	return Change{Severity: Safe, Action: "drop index", Table: op.Table, Detail: op.Index.Name}
+}
+
+func (op *AddForeignKey) Change() Change {
+	// existing rows are validated against the new constraint
+	if op.Replaces != nil {
+		detail := op.ForeignKey.Name + ": " + strings.Join(foreignKeyChanges(*op.Replaces, op.ForeignKey), ", ")
+		return Change{Severity: Blocking, Action: "recreate foreign key", Table: op.Table, Detail: detail}
+	}
+	return Change{Severity: Blocking, Action: "add foreign key", Table: op.Table, Detail: op.ForeignKey.Name}
+}
+
+func (op *DropForeignKey) Change() Change {
+	return Change{Severity: Safe, Action: "drop foreign key", Table: op.Table, Detail: op.ForeignKey.Name}
+}
+
+func (op *AddCheck) Change() Change {
+	// existing rows are validated against the new constraint
+	if op.Replaces != nil {
+		detail := op.Check.Name + ": " + strings.Join(checkChanges(*op.Replaces, op.Check), ", ")
+		return Change{Severity: Blocking, Action: "recreate check", Table: op.Table, Detail: detail}
+	}
+	return Change{Severity: Blocking, Action: "add check", Table: op.Table, Detail: op.Check.Name}
+}
+
+func (op *DropCheck) Change() Change {
+	return Change{Severity: Safe, Action: "drop check", Table: op.Table, Detail: op.Check.Name}
+}
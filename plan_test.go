@@ -0,0 +1,558 @@
+package migrator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type planLegacy struct {
+	ID   uint
+	Code string
+}
+
+func (planLegacy) TableName() string { return "legacy" }
+
+type planPerson struct {
+	ID       uint
+	Name     string
+	LegacyID uint
+	Legacy   planLegacy
+}
+
+func (planPerson) TableName() string { return "people" }
+
+type planPersonWithoutLegacy struct {
+	ID   uint
+	Name string
+}
+
+func (planPersonWithoutLegacy) TableName() string { return "people" }
+
+func TestPlanDropsConstraintsBeforeDroppedTables(t *testing.T) {
+	m := testMigrator(t, openSQLite(t))
+	migrateTo(t, m, &planPerson{})
+
+	m.Models = []interface{}{&planPersonWithoutLegacy{}}
+	plan := mustPlan(t, m)
+	dropForeignKey, dropTable, dropColumn := changeIndex(plan, "drop foreign key people"), changeIndex(plan, "drop table legacy"), changeIndex(plan, "drop column people.legacy_id")
+	if dropForeignKey < 0 || dropTable < 0 || dropColumn < 0 {
+		t.Fatalf("missing drops in %v", plan.Changes())
+	}
+	if !(dropForeignKey < dropTable && dropTable < dropColumn) {
+		t.Errorf("want the foreign key, then the table, then the column dropped, got %v", plan.Changes())
+	}
+
+	// the down migration recreates the table before the foreign key pointing at it
+	migrateTo(t, m, &planPersonWithoutLegacy{})
+	assertNoDrift(t, m)
+	mustRun(t, m, "down")
+	m.Models = []interface{}{&planPerson{}}
+	assertNoDrift(t, m)
+}
+
+type planAuthor struct {
+	ID   uint
+	Name string `gorm:"size:64"`
+}
+
+func (planAuthor) TableName() string { return "plan_authors" }
+
+type planAuthorWithBio struct {
+	ID   uint
+	Name string `gorm:"size:64"`
+	Bio  string `gorm:"size:255"`
+}
+
+func (planAuthorWithBio) TableName() string { return "plan_authors" }
+
+type planAuthorWithSummary struct {
+	ID      uint
+	Name    string `gorm:"size:64"`
+	Summary string `gorm:"size:255"`
+}
+
+func (planAuthorWithSummary) TableName() string { return "plan_authors" }
+
+type planAuthorWithAge struct {
+	ID   uint
+	Name string `gorm:"size:64"`
+	Age  int
+}
+
+func (planAuthorWithAge) TableName() string { return "plan_authors" }
+
+type planAuthorRenamedColumn struct {
+	ID       uint
+	FullName string `gorm:"size:64" migrator:"renamed_from:name"`
+}
+
+func (planAuthorRenamedColumn) TableName() string { return "plan_authors" }
+
+type planAuthorWiderName struct {
+	ID   uint
+	Name string `gorm:"size:128;not null;default:'anonymous'"`
+}
+
+func (planAuthorWiderName) TableName() string { return "plan_authors" }
+
+type planAuthorIndexed struct {
+	ID   uint
+	Name string `gorm:"size:64;index:idx_plan_authors_name"`
+}
+
+func (planAuthorIndexed) TableName() string { return "plan_authors" }
+
+type planAuthorUniquelyIndexed struct {
+	ID   uint
+	Name string `gorm:"size:64;uniqueIndex:idx_plan_authors_name"`
+}
+
+func (planAuthorUniquelyIndexed) TableName() string { return "plan_authors" }
+
+type planAuthorPartiallyIndexed struct {
+	ID   uint
+	Name string `gorm:"size:64;index:idx_plan_authors_name,where:name <> ''"`
+}
+
+func (planAuthorPartiallyIndexed) TableName() string { return "plan_authors" }
+
+type planContact struct {
+	ID    uint
+	First string `gorm:"size:64;index:idx_plan_contacts_name,priority:1"`
+	Last  string `gorm:"size:64;index:idx_plan_contacts_name,priority:2"`
+}
+
+func (planContact) TableName() string { return "plan_contacts" }
+
+type planContactByLast struct {
+	ID    uint
+	First string `gorm:"size:64;index:idx_plan_contacts_name,priority:2"`
+	Last  string `gorm:"size:64;index:idx_plan_contacts_name,priority:1"`
+}
+
+func (planContactByLast) TableName() string { return "plan_contacts" }
+
+type planWriter struct {
+	ID   uint
+	Name string `gorm:"size:64"`
+}
+
+func (planWriter) TableName() string { return "plan_writers" }
+
+func (planWriter) PreviousTableNames() []string { return []string{"plan_authors"} }
+
+type planBook struct {
+	ID       uint
+	Price    float64 `gorm:"type:decimal(10,2)"`
+	AuthorID uint
+}
+
+func (planBook) TableName() string { return "plan_books" }
+
+type planBookWithAuthor struct {
+	ID       uint
+	Price    float64 `gorm:"type:decimal(10,2)"`
+	AuthorID uint
+	Author   planAuthor
+}
+
+func (planBookWithAuthor) TableName() string { return "plan_books" }
+
+type planBookChecked struct {
+	ID       uint
+	Price    float64 `gorm:"type:decimal(10,2);check:chk_plan_books_price,price > 0"`
+	AuthorID uint
+}
+
+func (planBookChecked) TableName() string { return "plan_books" }
+
+type planBookCheckedAtLeastZero struct {
+	ID       uint
+	Price    float64 `gorm:"type:decimal(10,2);check:chk_plan_books_price,price >= 0"`
+	AuthorID uint
+}
+
+func (planBookCheckedAtLeastZero) TableName() string { return "plan_books" }
+
+type planBookReworked struct {
+	ID       uint
+	Price    float64 `gorm:"type:decimal(10,2);check:chk_plan_books_price,price >= 0"`
+	Title    string  `gorm:"size:64;index:idx_plan_books_title"`
+	AuthorID uint
+	Author   planAuthor
+}
+
+func (planBookReworked) TableName() string { return "plan_books" }
+
+type planBookCluttered struct {
+	ID       uint
+	Price    float64 `gorm:"type:decimal(10,2);check:chk_plan_books_price,price > 0"`
+	Title    string  `gorm:"size:64;index:idx_plan_books_title"`
+	Legacy   string  `gorm:"size:32"`
+	AuthorID uint
+	Author   planAuthor
+}
+
+func (planBookCluttered) TableName() string { return "plan_books" }
+
+type planBookSummarized struct {
+	ID       uint
+	Price    float64 `gorm:"type:decimal(10,2)"`
+	Title    string  `gorm:"size:64"`
+	Summary  string  `gorm:"size:255"`
+	AuthorID uint
+}
+
+func (planBookSummarized) TableName() string { return "plan_books" }
+
+// planCases change the schema from the before models to the after ones, with the changes their plan
+// describes in order
+var planCases = []struct {
+	name          string
+	before, after []interface{}
+	changes       []string
+}{
+	{
+		name:    "create table",
+		after:   []interface{}{&planAuthor{}},
+		changes: []string{"create table plan_authors"},
+	},
+	{
+		name:    "drop table",
+		before:  []interface{}{&planAuthor{}, &planBook{}},
+		after:   []interface{}{&planBook{}},
+		changes: []string{"drop table plan_authors"},
+	},
+	{
+		name:    "rename table",
+		before:  []interface{}{&planAuthor{}},
+		after:   []interface{}{&planWriter{}},
+		changes: []string{"rename table plan_writers (from plan_authors)"},
+	},
+	{
+		name:    "add column",
+		before:  []interface{}{&planAuthor{}},
+		after:   []interface{}{&planAuthorWithBio{}},
+		changes: []string{"add column plan_authors.bio"},
+	},
+	{
+		name:    "drop column",
+		before:  []interface{}{&planAuthorWithBio{}},
+		after:   []interface{}{&planAuthor{}},
+		changes: []string{"drop column plan_authors.bio"},
+	},
+	{
+		name:    "rename column",
+		before:  []interface{}{&planAuthor{}},
+		after:   []interface{}{&planAuthorRenamedColumn{}},
+		changes: []string{"rename column plan_authors.full_name (from name)"},
+	},
+	{
+		name:   "alter column",
+		before: []interface{}{&planAuthor{}},
+		after:  []interface{}{&planAuthorWiderName{}},
+		// SQLite declares every string as text, the size is not part of its type
+		changes: []string{"alter column plan_authors.name (nullable, default)"},
+	},
+	{
+		name:    "create index",
+		before:  []interface{}{&planAuthor{}},
+		after:   []interface{}{&planAuthorIndexed{}},
+		changes: []string{"create index plan_authors (idx_plan_authors_name)"},
+	},
+	{
+		name:    "drop index",
+		before:  []interface{}{&planAuthorIndexed{}},
+		after:   []interface{}{&planAuthor{}},
+		changes: []string{"drop index plan_authors (idx_plan_authors_name)"},
+	},
+	{
+		name:   "recreate unique index",
+		before: []interface{}{&planAuthorIndexed{}},
+		after:  []interface{}{&planAuthorUniquelyIndexed{}},
+		changes: []string{
+			"drop index plan_authors (idx_plan_authors_name)",
+			"recreate index plan_authors (idx_plan_authors_name: unique)",
+		},
+	},
+	{
+		name:   "recreate index with other key order",
+		before: []interface{}{&planContact{}},
+		after:  []interface{}{&planContactByLast{}},
+		changes: []string{
+			"drop index plan_contacts (idx_plan_contacts_name)",
+			"recreate index plan_contacts (idx_plan_contacts_name: keys (`first`, `last`) to (last, first))",
+		},
+	},
+	{
+		name:   "recreate partial index",
+		before: []interface{}{&planAuthorIndexed{}},
+		after:  []interface{}{&planAuthorPartiallyIndexed{}},
+		changes: []string{
+			"drop index plan_authors (idx_plan_authors_name)",
+			`recreate index plan_authors (idx_plan_authors_name: predicate "" to "name <> ''")`,
+		},
+	},
+	{
+		name:    "add foreign key",
+		before:  []interface{}{&planAuthor{}, &planBook{}},
+		after:   []interface{}{&planAuthor{}, &planBookWithAuthor{}},
+		changes: []string{"add foreign key plan_books (fk_plan_books_author)"},
+	},
+	{
+		name:    "drop foreign key",
+		before:  []interface{}{&planAuthor{}, &planBookWithAuthor{}},
+		after:   []interface{}{&planAuthor{}, &planBook{}},
+		changes: []string{"drop foreign key plan_books (fk_plan_books_author)"},
+	},
+	{
+		name:    "add check",
+		before:  []interface{}{&planBook{}},
+		after:   []interface{}{&planBookChecked{}},
+		changes: []string{"add check plan_books (chk_plan_books_price)"},
+	},
+	{
+		name:    "drop check",
+		before:  []interface{}{&planBookChecked{}},
+		after:   []interface{}{&planBook{}},
+		changes: []string{"drop check plan_books (chk_plan_books_price)"},
+	},
+	{
+		name:   "replace check",
+		before: []interface{}{&planBookChecked{}},
+		after:  []interface{}{&planBookCheckedAtLeastZero{}},
+		changes: []string{
+			"drop check plan_books (chk_plan_books_price)",
+			`recreate check plan_books (chk_plan_books_price: expression "price > 0" to "price >= 0")`,
+		},
+	},
+	{
+		// a table drops its foreign keys, checks and indexes before its columns, and adds columns after
+		name:   "table ordering",
+		before: []interface{}{&planAuthor{}, &planBookCluttered{}},
+		after:  []interface{}{&planAuthor{}, &planBookSummarized{}},
+		changes: []string{
+			"drop foreign key plan_books (fk_plan_books_author)",
+			"drop check plan_books (chk_plan_books_price)",
+			"drop index plan_books (idx_plan_books_title)",
+			"drop column plan_books.legacy",
+			"add column plan_books.summary",
+		},
+	},
+	{
+		// what every table drops goes first, then each table's columns, indexes, checks and foreign keys
+		name:   "ordering",
+		before: []interface{}{&planAuthorIndexed{}, &planBookChecked{}},
+		after:  []interface{}{&planAuthor{}, &planBookReworked{}},
+		changes: []string{
+			"drop index plan_authors (idx_plan_authors_name)",
+			"drop check plan_books (chk_plan_books_price)",
+			"add column plan_books.title",
+			"create index plan_books (idx_plan_books_title)",
+			`recreate check plan_books (chk_plan_books_price: expression "price > 0" to "price >= 0")`,
+			"add foreign key plan_books (fk_plan_books_author)",
+		},
+	},
+}
+
+func TestPlan(t *testing.T) {
+	for _, tc := range planCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := testMigrator(t, openSQLite(t))
+			if len(tc.before) > 0 {
+				migrateTo(t, m, tc.before...)
+			}
+
+			m.Models = tc.after
+			assertChanges(t, mustPlan(t, m), tc.changes...)
+
+			// the up migration leaves nothing to do, the down migration restores the schema it started from
+			migrateTo(t, m, tc.after...)
+			assertNoDrift(t, m)
+			mustRun(t, m, "down")
+			m.Models = tc.before
+			if len(tc.before) > 0 {
+				assertNoDrift(t, m)
+			}
+		})
+	}
+}
+
+func TestPlanPossibleRename(t *testing.T) {
+	dropAndAdd := []string{"drop column plan_authors.bio", "add column plan_authors.summary"}
+	tests := []struct {
+		name             string
+		after            interface{}
+		allowDestructive bool
+		// answer is what Confirm answers, Confirm is unset when it is empty
+		answer  string
+		asked   bool
+		changes []string
+		err     *PossibleRenameError
+	}{
+		{
+			name:  "without Confirm",
+			after: &planAuthorWithSummary{},
+			err:   &PossibleRenameError{Table: "plan_authors", From: "bio", To: "summary"},
+		},
+		{
+			name:             "without Confirm allowing destructive changes",
+			after:            &planAuthorWithSummary{},
+			allowDestructive: true,
+			changes:          dropAndAdd,
+		},
+		{
+			name:    "confirmed",
+			after:   &planAuthorWithSummary{},
+			answer:  "yes",
+			asked:   true,
+			changes: []string{"rename column plan_authors.summary (from bio)"},
+		},
+		{
+			name:    "declined",
+			after:   &planAuthorWithSummary{},
+			answer:  "no",
+			asked:   true,
+			changes: dropAndAdd,
+		},
+		{
+			// a column of another type is never taken for the dropped one
+			name:    "another type",
+			after:   &planAuthorWithAge{},
+			answer:  "yes",
+			changes: []string{"drop column plan_authors.bio", "add column plan_authors.age"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testMigrator(t, openSQLite(t))
+			migrateTo(t, m, &planAuthorWithBio{})
+
+			var questions []string
+			m.AllowDestructive = tt.allowDestructive
+			if tt.answer != "" {
+				m.Confirm = func(question string) bool {
+					questions = append(questions, question)
+					return tt.answer == "yes"
+				}
+			}
+			m.Models = []interface{}{tt.after}
+			plan, err := m.Plan()
+			if asked := len(questions) > 0; asked != tt.asked {
+				t.Errorf("asked %q, want asked %v", questions, tt.asked)
+			}
+			if tt.err != nil {
+				var possible *PossibleRenameError
+				if !errors.As(err, &possible) || !errors.Is(err, ErrPossibleRename) || *possible != *tt.err {
+					t.Fatalf("Plan = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Plan: %v", err)
+			}
+			assertChanges(t, plan, tt.changes...)
+
+			m.AllowDestructive = true
+			migrateTo(t, m, tt.after)
+			assertNoDrift(t, m)
+			mustRun(t, m, "down")
+			m.Models = []interface{}{&planAuthorWithBio{}}
+			assertNoDrift(t, m)
+		})
+	}
+}
+
+// droppedTables returns the tables plan drops
+func droppedTables(plan *Plan) []string {
+	var tables []string
+	for _, op := range plan.Operations {
+		if op, ok := op.(*DropTable); ok {
+			tables = append(tables, op.Table)
+		}
+	}
+	return tables
+}
+
+func TestPlanKeepsTables(t *testing.T) {
+	tests := []struct {
+		name    string
+		keep    func(m *Migrator)
+		dropped []string
+	}{
+		{
+			name:    "none kept",
+			keep:    func(m *Migrator) {},
+			dropped: []string{"audit_log", "plan_writers"},
+		},
+		{
+			name:    "ignored by glob",
+			keep:    func(m *Migrator) { m.IgnoreTables = []string{"audit_*"} },
+			dropped: []string{"plan_writers"},
+		},
+		{
+			name:    "ignored by pattern",
+			keep:    func(m *Migrator) { m.IgnoreTablePatterns = []*regexp.Regexp{regexp.MustCompile(`^audit_`)} },
+			dropped: []string{"plan_writers"},
+		},
+		{
+			// plan_writers was renamed by a migration, audit_log created by hand
+			name:    "unmanaged",
+			keep:    func(m *Migrator) { m.ManagedTablesOnly = true },
+			dropped: []string{"plan_writers"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testMigrator(t, openSQLite(t))
+			migrateTo(t, m, &planAuthor{}, &planBook{})
+			migrateTo(t, m, &planWriter{}, &planBook{})
+			mustExec(t, m.DB, "CREATE TABLE audit_log (id integer)")
+
+			tt.keep(m)
+			m.Models = []interface{}{&planBook{}}
+			got := droppedTables(mustPlan(t, m))
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tt.dropped, ",") {
+				t.Errorf("dropped tables = %v, want %v", got, tt.dropped)
+			}
+		})
+	}
+}
+
+func TestManagedTables(t *testing.T) {
+	m := testMigrator(t, openSQLite(t))
+	migrations := map[string]string{
+		"1_postgres.up.sql": `CREATE TABLE "public"."plan_authors" ("id" bigserial);
+ALTER TABLE "plan_authors" RENAME TO "plan_writers";`,
+		"2_mysql.up.sql": "CREATE TABLE IF NOT EXISTS `plan_books` (`id` bigint);\nALTER TABLE `plan_books` RENAME TO `books`;",
+		"3_sqlserver.up.sql": `CREATE TABLE "plan_tags" ("id" bigint);
+EXEC sp_rename 'plan_tags', 'tags';
+EXEC sp_rename 'tags.name', 'label', 'COLUMN';`,
+		"4_commented.up.sql":   "-- CREATE TABLE commented (id int);",
+		"4_commented.down.sql": "CREATE TABLE restored (id int);",
+	}
+	for name, sql := range migrations {
+		if err := os.WriteFile(filepath.Join(m.migrationPath, name), []byte(sql), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tables, err := m.managedTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for table := range tables {
+		got = append(got, table)
+	}
+	sort.Strings(got)
+	if want := "books,plan_authors,plan_books,plan_tags,plan_writers,tags"; strings.Join(got, ",") != want {
+		t.Errorf("managedTables() = %v, want %s", got, want)
+	}
+}
@@ -0,0 +1,186 @@
+package migrator
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// TableRebuilder is implemented by dialects whose ALTER TABLE cannot apply every operation, the operations
+// on such a table are applied together by rebuilding it
+type TableRebuilder interface {
+	// RebuiltTables returns the tables some of ops can only be applied to by rebuilding them
+	RebuiltTables(m *Migrator, ops []Operation) (map[string]bool, error)
+	// RebuildTableSQL returns the statements that rebuild table with ops applied, and those that rebuild it
+	// back the way it was
+	RebuildTableSQL(m *Migrator, table string, ops []Operation) (string, string, error)
+	// ForeignKeysSQL returns the statement that turns foreign key enforcement on or off around the
+	// transaction of a migration that rebuilds tables
+	ForeignKeysSQL(enabled bool) string
+}
+
+// renderedStep is the up and down SQL of an operation, or of all the operations on a rebuilt table
+type renderedStep struct {
+	table    string
+	up, down string
+}
+
+// Render turns plan into up and down migrations for the migrator's dialect, the down migration
+// undoes the operations in reverse order. Both are empty when the plan is.
+func (m *Migrator) Render(plan *Plan) (string, string, error) {
+	if plan.Empty() {
+		return "", "", nil
+	}
+
+	d, err := m.dialect()
+	if err != nil {
+		return "", "", err
+	}
+
+	rebuilder, _ := d.(TableRebuilder)
+	rebuilt := map[string]bool{}
+	if rebuilder != nil {
+		if rebuilt, err = rebuilder.RebuiltTables(m, plan.Operations); err != nil {
+			return "", "", err
+		}
+	}
+
+	// the operations before a rebuilt table is renamed are part of its rebuild
+	renamedTo := map[string]string{}
+	for _, op := range plan.Operations {
+		if op, ok := op.(*RenameTable); ok && rebuilt[op.Table] {
+			renamedTo[op.From] = op.Table
+		}
+	}
+	rebuiltTableOf := func(op Operation) string {
+		if table, ok := renamedTo[tableOf(op)]; ok {
+			return table
+		}
+		return tableOf(op)
+	}
+
+	var (
+		steps   []renderedStep
+		applied = map[string]bool{}
+	)
+	for i, op := range plan.Operations {
+		step := renderedStep{table: rebuiltTableOf(op)}
+		if rebuilt[step.table] {
+			// every operation on the table is applied by the one rebuild
+			if applied[step.table] {
+				continue
+			}
+			applied[step.table] = true
+			var ops []Operation
+			for _, other := range plan.Operations[i:] {
+				if rebuiltTableOf(other) == step.table {
+					ops = append(ops, other)
+				}
+			}
+			step.up, step.down, err = rebuilder.RebuildTableSQL(m, step.table, ops)
+		} else {
+			step.up, step.down, err = m.renderOperation(d, op)
+		}
+		if err != nil {
+			return "", "", err
+		}
+		steps = append(steps, step)
+	}
+
+	// a blank line separates the statements of consecutive tables
+	var up, down strings.Builder
+	for i := range steps {
+		j := len(steps) - 1 - i
+		if i > 0 && steps[i].table != steps[i-1].table {
+			up.WriteString("\n")
+		}
+		if i > 0 && steps[j].table != steps[j+1].table {
+			down.WriteString("\n")
+		}
+		up.WriteString(steps[i].up)
+		down.WriteString(steps[j].down)
+	}
+
+	begin := d.BeginSQL()
+	upSQL, downSQL := begin+"\n\n"+up.String()+"\nCOMMIT;", begin+"\n\n"+down.String()+"\nCOMMIT;"
+	if len(rebuilt) > 0 {
+		// foreign keys are only enforced again once every table they reference is back in place
+		off, on := rebuilder.ForeignKeysSQL(false), rebuilder.ForeignKeysSQL(true)
+		upSQL, downSQL = off+"\n\n"+upSQL+"\n\n"+on, off+"\n\n"+downSQL+"\n\n"+on
+	}
+	return upSQL, downSQL, nil
+}
+
+// renderOperation returns the statements that apply op and the statements that undo it
+func (m *Migrator) renderOperation(d Dialect, op Operation) (up string, down string, err error) {
+	switch op := op.(type) {
+	case *CreateTable:
+		up, down = m.CreateTable(op.Model)
+	case *AddColumn:
+		err = m.RunWithValue(op.Model, func(stmt *gorm.Statement) error {
+			up = m.AddColumn(op.Model, op.Field.DBName)
+			down = m.DropColumn(stmt, op.Field.DBName)
+			return nil
+		})
+	case *RenameTable:
+		err = m.RunWithValue(op.From, func(from *gorm.Statement) error {
+			return m.RunWithValue(op.Table, func(to *gorm.Statement) error {
+				up = d.RenameTableSQL(m, from, op.Table)
+				down = d.RenameTableSQL(m, to, op.From)
+				return nil
+			})
+		})
+	case *RenameColumn:
+		err = m.RunWithValue(op.Model, func(stmt *gorm.Statement) error {
+			renamed := op.Column
+			renamed.NameValue = sql.NullString{String: op.Field.DBName, Valid: true}
+			up = d.RenameColumnSQL(m, stmt, op.Column, op.Field.DBName)
+			down = d.RenameColumnSQL(m, stmt, renamed, op.Column.Name())
+			return nil
+		})
+	case *AlterColumn:
+		err = m.RunWithValue(op.Model, func(stmt *gorm.Statement) error {
+			up = d.AlterColumnSQL(m, stmt, m.columnTypeOf(op.After), op.Changes)
+			down = d.AlterColumnSQL(m, stmt, op.Before, op.Changes)
+			return nil
+		})
+	case *CreateIndex:
+		up, down = m.CreateIndex(op.Model, op.Index.Name)
+	default:
+		err = m.RunWithValue(tableOf(op), func(stmt *gorm.Statement) error {
+			up, down, err = m.renderCatalogOperation(d, stmt, op)
+			return err
+		})
+	}
+	return up, down, err
+}
+
+// renderCatalogOperation renders the operations described by catalog metadata rather than a model
+func (m *Migrator) renderCatalogOperation(d Dialect, stmt *gorm.Statement, op Operation) (string, string, error) {
+	switch op := op.(type) {
+	case *DropTable:
+		dependents := &tableDependents{dialect: d, indexes: op.Indexes, foreignKeys: op.ForeignKeys, checks: op.Checks}
+		return "-- Drop Table \n" + buildRawSQL(m.DB, "DROP TABLE ?", m.CurrentTable(stmt)),
+			m.createTableFromCatalog(stmt, op.Columns, dependents), nil
+	case *DropColumn:
+		return m.DropColumn(stmt, op.Column.Name()), d.AddColumnSQL(m, stmt, op.Column), nil
+	case *DropIndex:
+		return d.DropIndexSQL(m, stmt, op.Index), op.Index.Definition + "; \n", nil
+	case *AddForeignKey:
+		return d.AddForeignKeySQL(m, stmt, op.ForeignKey), d.DropForeignKeySQL(m, stmt, op.ForeignKey), nil
+	case *DropForeignKey:
+		return d.DropForeignKeySQL(m, stmt, op.ForeignKey), d.AddForeignKeySQL(m, stmt, op.ForeignKey), nil
+	case *AddCheck:
+		return d.AddCheckSQL(m, stmt, op.Check), d.DropCheckSQL(m, stmt, op.Check), nil
+	case *DropCheck:
+		return d.DropCheckSQL(m, stmt, op.Check), d.AddCheckSQL(m, stmt, op.Check), nil
+	}
+	return "", "", fmt.Errorf("cannot render operation %T", op)
+}
+
+// tableOf returns the table op changes
+func tableOf(op Operation) string {
+	return op.Change().Table
+}
@@ -0,0 +1,224 @@
+package migrator
+
+import (
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// offlineMigrator returns a migrator generating migrations for dialector from the snapshots in its folder,
+// without connecting to a database
+func offlineMigrator(t *testing.T, dialector gorm.Dialector) *Migrator {
+	t.Helper()
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Discard, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := testMigrator(t, db)
+	m.Offline = true
+	return m
+}
+
+// renderBetween returns the up and down migration m generates from the before models to the after ones
+func renderBetween(t *testing.T, m *Migrator, before, after []interface{}) (string, string) {
+	t.Helper()
+	if len(before) > 0 {
+		m.Models = before
+		mustRun(t, m, "create", "before")
+	}
+	m.Models = after
+	up, down, err := m.AutoMigrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return up, down
+}
+
+// statements returns the statements of a migration without its transaction, comments and blank lines
+func statements(migration string) []string {
+	var result []string
+	for _, line := range strings.Split(migration, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line == "BEGIN;" || line == "COMMIT;" || strings.HasPrefix(line, "--") {
+			continue
+		}
+		result = append(result, line)
+	}
+	return result
+}
+
+// assertStatements fails the test unless the statements of migration are want, in order
+func assertStatements(t *testing.T, name, migration string, want ...string) {
+	t.Helper()
+	if got := statements(migration); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("%s statements:\n\t%s\nwant:\n\t%s", name, strings.Join(got, "\n\t"), strings.Join(want, "\n\t"))
+	}
+}
+
+// postgresRenders are the statements of the up and down migrations of each of planCases on Postgres
+var postgresRenders = map[string]struct{ up, down []string }{
+	"create table": {
+		up:   []string{`CREATE TABLE "plan_authors" ("id" bigserial,"name" varchar(64),PRIMARY KEY ("id"));`},
+		down: []string{`DROP TABLE IF EXISTS "plan_authors";`},
+	},
+	"drop table": {
+		up:   []string{`DROP TABLE "plan_authors";`},
+		down: []string{`CREATE TABLE "plan_authors" ("id" bigserial NOT NULL,"name" varchar(64),PRIMARY KEY ("id"));`},
+	},
+	"rename table": {
+		up:   []string{`ALTER TABLE "plan_authors" RENAME TO "plan_writers";`},
+		down: []string{`ALTER TABLE "plan_writers" RENAME TO "plan_authors";`},
+	},
+	"add column": {
+		up:   []string{`ALTER TABLE "plan_authors" ADD "bio" varchar(255);`},
+		down: []string{`ALTER TABLE "plan_authors" DROP COLUMN "bio";`},
+	},
+	"drop column": {
+		up:   []string{`ALTER TABLE "plan_authors" DROP COLUMN "bio";`},
+		down: []string{`ALTER TABLE "plan_authors" ADD "bio" varchar(255);`},
+	},
+	"rename column": {
+		up:   []string{`ALTER TABLE "plan_authors" RENAME COLUMN "name" TO "full_name";`},
+		down: []string{`ALTER TABLE "plan_authors" RENAME COLUMN "full_name" TO "name";`},
+	},
+	"alter column": {
+		up: []string{
+			`ALTER TABLE "plan_authors" ALTER COLUMN "name" TYPE varchar(128) USING "name"::varchar(128);`,
+			`ALTER TABLE "plan_authors" ALTER COLUMN "name" SET NOT NULL;`,
+			`ALTER TABLE "plan_authors" ALTER COLUMN "name" SET DEFAULT 'anonymous';`,
+		},
+		down: []string{
+			`ALTER TABLE "plan_authors" ALTER COLUMN "name" TYPE varchar(64) USING "name"::varchar(64);`,
+			`ALTER TABLE "plan_authors" ALTER COLUMN "name" DROP NOT NULL;`,
+			`ALTER TABLE "plan_authors" ALTER COLUMN "name" DROP DEFAULT;`,
+		},
+	},
+	"create index": {
+		up:   []string{`CREATE INDEX "idx_plan_authors_name" ON "plan_authors" ("name");`},
+		down: []string{`DROP INDEX "idx_plan_authors_name";`},
+	},
+	"recreate unique index": {
+		up:   []string{`DROP INDEX "idx_plan_authors_name";`, `CREATE UNIQUE INDEX "idx_plan_authors_name" ON "plan_authors" ("name");`},
+		down: []string{`DROP INDEX "idx_plan_authors_name";`, `CREATE INDEX "idx_plan_authors_name" ON "plan_authors" ("name");`},
+	},
+	"recreate index with other key order": {
+		up:   []string{`DROP INDEX "idx_plan_contacts_name";`, `CREATE INDEX "idx_plan_contacts_name" ON "plan_contacts" ("last","first");`},
+		down: []string{`DROP INDEX "idx_plan_contacts_name";`, `CREATE INDEX "idx_plan_contacts_name" ON "plan_contacts" ("first","last");`},
+	},
+	"recreate partial index": {
+		up:   []string{`DROP INDEX "idx_plan_authors_name";`, `CREATE INDEX "idx_plan_authors_name" ON "plan_authors" ("name") WHERE name <> '';`},
+		down: []string{`DROP INDEX "idx_plan_authors_name";`, `CREATE INDEX "idx_plan_authors_name" ON "plan_authors" ("name");`},
+	},
+	"drop index": {
+		up:   []string{`DROP INDEX "idx_plan_authors_name";`},
+		down: []string{`CREATE INDEX "idx_plan_authors_name" ON "plan_authors" ("name");`},
+	},
+	"add foreign key": {
+		up:   []string{`ALTER TABLE "plan_books" ADD CONSTRAINT "fk_plan_books_author" FOREIGN KEY ("author_id") REFERENCES "plan_authors"("id");`},
+		down: []string{`ALTER TABLE "plan_books" DROP CONSTRAINT "fk_plan_books_author";`},
+	},
+	"drop foreign key": {
+		up:   []string{`ALTER TABLE "plan_books" DROP CONSTRAINT "fk_plan_books_author";`},
+		down: []string{`ALTER TABLE "plan_books" ADD CONSTRAINT "fk_plan_books_author" FOREIGN KEY ("author_id") REFERENCES "plan_authors"("id");`},
+	},
+	"add check": {
+		up:   []string{`ALTER TABLE "plan_books" ADD CONSTRAINT "chk_plan_books_price" CHECK (price > 0);`},
+		down: []string{`ALTER TABLE "plan_books" DROP CONSTRAINT "chk_plan_books_price";`},
+	},
+	"drop check": {
+		up:   []string{`ALTER TABLE "plan_books" DROP CONSTRAINT "chk_plan_books_price";`},
+		down: []string{`ALTER TABLE "plan_books" ADD CONSTRAINT "chk_plan_books_price" CHECK (price > 0);`},
+	},
+	"replace check": {
+		up: []string{
+			`ALTER TABLE "plan_books" DROP CONSTRAINT "chk_plan_books_price";`,
+			`ALTER TABLE "plan_books" ADD CONSTRAINT "chk_plan_books_price" CHECK (price >= 0);`,
+		},
+		down: []string{
+			`ALTER TABLE "plan_books" DROP CONSTRAINT "chk_plan_books_price";`,
+			`ALTER TABLE "plan_books" ADD CONSTRAINT "chk_plan_books_price" CHECK (price > 0);`,
+		},
+	},
+	"table ordering": {
+		up: []string{
+			`ALTER TABLE "plan_books" DROP CONSTRAINT "fk_plan_books_author";`,
+			`ALTER TABLE "plan_books" DROP CONSTRAINT "chk_plan_books_price";`,
+			`DROP INDEX "idx_plan_books_title";`,
+			`ALTER TABLE "plan_books" DROP COLUMN "legacy";`,
+			`ALTER TABLE "plan_books" ADD "summary" varchar(255);`,
+		},
+		down: []string{
+			`ALTER TABLE "plan_books" DROP COLUMN "summary";`,
+			`ALTER TABLE "plan_books" ADD "legacy" varchar(32);`,
+			`CREATE INDEX "idx_plan_books_title" ON "plan_books" ("title");`,
+			`ALTER TABLE "plan_books" ADD CONSTRAINT "chk_plan_books_price" CHECK (price > 0);`,
+			`ALTER TABLE "plan_books" ADD CONSTRAINT "fk_plan_books_author" FOREIGN KEY ("author_id") REFERENCES "plan_authors"("id");`,
+		},
+	},
+	"ordering": {
+		up: []string{
+			`DROP INDEX "idx_plan_authors_name";`,
+			`ALTER TABLE "plan_books" DROP CONSTRAINT "chk_plan_books_price";`,
+			`ALTER TABLE "plan_books" ADD "title" varchar(64);`,
+			`CREATE INDEX "idx_plan_books_title" ON "plan_books" ("title");`,
+			`ALTER TABLE "plan_books" ADD CONSTRAINT "chk_plan_books_price" CHECK (price >= 0);`,
+			`ALTER TABLE "plan_books" ADD CONSTRAINT "fk_plan_books_author" FOREIGN KEY ("author_id") REFERENCES "plan_authors"("id");`,
+		},
+		down: []string{
+			`ALTER TABLE "plan_books" DROP CONSTRAINT "fk_plan_books_author";`,
+			`ALTER TABLE "plan_books" DROP CONSTRAINT "chk_plan_books_price";`,
+			`DROP INDEX "idx_plan_books_title";`,
+			`ALTER TABLE "plan_books" DROP COLUMN "title";`,
+			`ALTER TABLE "plan_books" ADD CONSTRAINT "chk_plan_books_price" CHECK (price > 0);`,
+			`CREATE INDEX "idx_plan_authors_name" ON "plan_authors" ("name");`,
+		},
+	},
+}
+
+func TestRenderPostgres(t *testing.T) {
+	for _, tc := range planCases {
+		t.Run(tc.name, func(t *testing.T) {
+			want, ok := postgresRenders[tc.name]
+			if !ok {
+				t.Fatalf("no statements for %s", tc.name)
+			}
+			up, down := renderBetween(t, offlineMigrator(t, postgres.Open("host=localhost")), tc.before, tc.after)
+			assertStatements(t, "up", up, want.up...)
+			assertStatements(t, "down", down, want.down...)
+		})
+	}
+}
+
+func TestRenderDropsConstraintsBeforeDroppedTables(t *testing.T) {
+	up, down := renderBetween(t, offlineMigrator(t, postgres.Open("host=localhost")),
+		[]interface{}{&planPerson{}}, []interface{}{&planPersonWithoutLegacy{}})
+	assertStatements(t, "up", up,
+		`ALTER TABLE "people" DROP CONSTRAINT "fk_people_legacy";`,
+		`DROP TABLE "legacy";`,
+		`ALTER TABLE "people" DROP COLUMN "legacy_id";`,
+	)
+	assertStatements(t, "down", down,
+		`ALTER TABLE "people" ADD "legacy_id" bigint;`,
+		`CREATE TABLE "legacy" ("id" bigserial NOT NULL,"code" text,PRIMARY KEY ("id"));`,
+		`ALTER TABLE "people" ADD CONSTRAINT "fk_people_legacy" FOREIGN KEY ("legacy_id") REFERENCES "legacy"("id");`,
+	)
+}
+
+func TestRenderEmptyPlan(t *testing.T) {
+	m := offlineMigrator(t, postgres.Open("host=localhost"))
+	up, down, err := m.Render(&Plan{})
+	if err != nil || up != "" || down != "" {
+		t.Errorf("Render(empty plan) = %q, %q, %v, want nothing", up, down, err)
+	}
+}
+
+func TestRenderPostgresSerialAlter(t *testing.T) {
+	// a serial type only exists in CREATE TABLE, altering one changes the integer type behind it
+	up, down := renderBetween(t, offlineMigrator(t, postgres.Open("host=localhost")),
+		[]interface{}{&alterCounter{}}, []interface{}{&alterCounterWide{}})
+	assertStatements(t, "up", up, `ALTER TABLE "alter_counters" ALTER COLUMN "id" TYPE int8 USING "id"::int8;`)
+	assertStatements(t, "down", down, `ALTER TABLE "alter_counters" ALTER COLUMN "id" TYPE int4 USING "id"::int4;`)
+}
@@ -0,0 +1,65 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// SchemaResetter is implemented by dialects whose schemas hold more than golang-migrate's Drop removes,
+// like Postgres types and functions or SQLite views, the shadow database is reset with it
+type SchemaResetter interface {
+	// ResetSchema drops everything in the current schema of m's database
+	ResetSchema(m *Migrator) error
+}
+
+// withShadow calls fn with a migrator comparing the models with ShadowDB once the migration folder is
+// applied to it, and resets ShadowDB afterwards
+func (mg *Migrator) withShadow(fn func(target *Migrator) error) (err error) {
+	if mg.ShadowDB == mg.DB {
+		return errors.New("the shadow database must not be the migrated one, everything in it is dropped")
+	}
+
+	shadow := *mg
+	shadow.DB, shadow.Dialector, shadow.ShadowDB = mg.ShadowDB, mg.ShadowDB.Dialector, nil
+	if err := shadow.resetShadow(); err != nil {
+		return err
+	}
+	defer func() {
+		if resetErr := shadow.resetShadow(); err == nil {
+			err = resetErr
+		}
+	}()
+
+	m, err := shadow.newMigrate(shadow.DB)
+	if err != nil {
+		return err
+	}
+	// an empty migration folder leaves the shadow database empty
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error applying migrations to the shadow database: %w", err)
+	}
+	return fn(&shadow)
+}
+
+// resetShadow drops everything in the migrator's database, which is the shadow database
+func (mg *Migrator) resetShadow() error {
+	d, err := mg.dialect()
+	if err != nil {
+		return err
+	}
+	if resetter, ok := d.(SchemaResetter); ok {
+		err = resetter.ResetSchema(mg)
+	} else {
+		var m *migrate.Migrate
+		if m, err = mg.newMigrate(mg.DB); err == nil {
+			err = m.Drop()
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("error resetting the shadow database: %w", err)
+	}
+	return nil
+}
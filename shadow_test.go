@@ -0,0 +1,98 @@
+package migrator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// tablesOf returns the tables of db
+func tablesOf(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tables
+}
+
+func TestShadowMustNotBeDB(t *testing.T) {
+	m := testMigrator(t, openSQLite(t))
+	migrateTo(t, m, &planAuthor{})
+	m.ShadowDB = m.DB
+
+	m.Models = []interface{}{&planAuthorWithBio{}}
+	if err := m.Run(m.DB, "create", "add_bio"); err == nil {
+		t.Fatal("create succeeded with the migrated database as the shadow one")
+	}
+	if has, err := m.hasTable(&planAuthor{}); err != nil || !has {
+		t.Errorf("HasTable(plan_authors) = %v, %v, want the migrated database left alone", has, err)
+	}
+}
+
+func TestShadowCreate(t *testing.T) {
+	tests := []struct {
+		name string
+		// diverge takes the database away from the migration folder it was migrated with
+		diverge func(t *testing.T, m *Migrator)
+	}{
+		{
+			name: "database ahead of the folder",
+			diverge: func(t *testing.T, m *Migrator) {
+				mustExec(t, m.DB, "ALTER TABLE plan_authors ADD COLUMN bio varchar(255)")
+			},
+		},
+		{
+			name: "database behind the folder",
+			diverge: func(t *testing.T, m *Migrator) {
+				mustRun(t, m, "down")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testMigrator(t, openSQLite(t))
+			migrateTo(t, m, &planAuthor{})
+			tt.diverge(t, m)
+			m.ShadowDB = openSQLite(t)
+
+			// the migration follows from the folder's history, whatever the migrated database holds
+			m.Models = []interface{}{&planAuthorWithBio{}}
+			mustRun(t, m, "create", "add_bio")
+			up, err := os.ReadFile(filepath.Join(m.migrationPath, "2_add_bio.up.sql"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := statements(string(up)); strings.Join(got, "\n") != "ALTER TABLE `plan_authors` ADD `bio` text;" {
+				t.Errorf("up statements = %q, want only the bio column added", got)
+			}
+			if tables := tablesOf(t, m.ShadowDB); len(tables) > 0 {
+				t.Errorf("shadow tables = %v, want none", tables)
+			}
+		})
+	}
+}
+
+func TestShadowResetAfterFailure(t *testing.T) {
+	m := testMigrator(t, openSQLite(t))
+	migrateTo(t, m, &planAuthor{})
+	m.ShadowDB = openSQLite(t)
+
+	failure := errors.New("failure")
+	err := m.withShadow(func(target *Migrator) error {
+		if has, err := target.hasTable(&planAuthor{}); err != nil || !has {
+			t.Errorf("HasTable(plan_authors) = %v, %v on the shadow database, want the folder applied", has, err)
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("withShadow = %v, want the failure of fn", err)
+	}
+	if tables := tablesOf(t, m.ShadowDB); len(tables) > 0 {
+		t.Errorf("shadow tables = %v, want none", tables)
+	}
+}
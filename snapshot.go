@@ -0,0 +1,252 @@
+package migrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var regSnapshotFile = regexp.MustCompile(`^(\d+)_.*\.snapshot\.json$`)
+
+// Snapshot is the schema of the registered models, written as JSON next to each migration "create" writes
+// so the next one can be generated offline, against the schema the last one left behind
+type Snapshot struct {
+	// Dialect is the name of the gorm.Dialector the types were rendered for
+	Dialect string          `json:"dialect"`
+	Tables  []SnapshotTable `json:"tables"`
+}
+
+// SnapshotTable is a table of a Snapshot, its indexes and constraints are described the way the catalog
+// reports them
+type SnapshotTable struct {
+	Name        string           `json:"name"`
+	Columns     []SnapshotColumn `json:"columns"`
+	Indexes     []Index          `json:"indexes,omitempty"`
+	ForeignKeys []ForeignKey     `json:"foreign_keys,omitempty"`
+	Checks      []Check          `json:"checks,omitempty"`
+}
+
+// SnapshotColumn is a column of a SnapshotTable
+type SnapshotColumn struct {
+	Name string `json:"name"`
+	// Type is the full column type, like varchar(64)
+	Type          string `json:"type"`
+	PrimaryKey    bool   `json:"primary_key,omitempty"`
+	AutoIncrement bool   `json:"auto_increment,omitempty"`
+	Unique        bool   `json:"unique,omitempty"`
+	Nullable      bool   `json:"nullable"`
+	Default       string `json:"default,omitempty"`
+	Comment       string `json:"comment,omitempty"`
+}
+
+// Snapshot describes the schema of the registered models, tables and their indexes and constraints in
+// name order and columns in the order CreateTable declares them, so the same models always give the same
+// snapshot
+func (m *Migrator) Snapshot() (*Snapshot, error) {
+	d, err := m.dialect()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{Dialect: m.DB.Dialector.Name(), Tables: []SnapshotTable{}}
+	for _, value := range m.ReorderModels(m.Models, true) {
+		if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			table := SnapshotTable{Name: stmt.Table, Columns: []SnapshotColumn{}}
+			for _, dbName := range stmt.Schema.DBNames {
+				if field := stmt.Schema.FieldsByDBName[dbName]; !field.IgnoreMigration {
+					table.Columns = append(table.Columns, m.snapshotColumnOf(field))
+				}
+			}
+
+			indexes := stmt.Schema.ParseIndexes()
+			for _, name := range sortedIndexNames(indexes) {
+				idx := indexes[name]
+				class, method := strings.ToUpper(idx.Class), strings.ToLower(idx.Type)
+				if class == "UNIQUE" {
+					class = ""
+				}
+				if method == "" {
+					method = "btree"
+				}
+				index := Index{
+					Name:       idx.Name,
+					Table:      stmt.Table,
+					Unique:     strings.EqualFold(idx.Class, "UNIQUE"),
+					Definition: strings.TrimSuffix(d.CreateIndexSQL(m, stmt, &idx), "; \n"),
+					Keys:       indexKeys(&idx),
+					Class:      class,
+					Method:     method,
+					Where:      idx.Where,
+				}
+				for _, opt := range idx.Fields {
+					index.Columns = append(index.Columns, opt.DBName)
+				}
+				table.Indexes = append(table.Indexes, index)
+			}
+
+			for _, fk := range m.modelForeignKeys(stmt) {
+				table.ForeignKeys = append(table.ForeignKeys, fk)
+			}
+			sort.Slice(table.ForeignKeys, func(i, j int) bool { return table.ForeignKeys[i].Name < table.ForeignKeys[j].Name })
+			for _, chk := range modelChecks(stmt) {
+				table.Checks = append(table.Checks, chk)
+			}
+			sort.Slice(table.Checks, func(i, j int) bool { return table.Checks[i].Name < table.Checks[j].Name })
+
+			snapshot.Tables = append(snapshot.Tables, table)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(snapshot.Tables, func(i, j int) bool { return snapshot.Tables[i].Name < snapshot.Tables[j].Name })
+	return snapshot, nil
+}
+
+// snapshotPath returns the file the snapshot of the migration upName is written to
+func snapshotPath(upName string) string {
+	return strings.TrimSuffix(upName, ".up.sql") + ".snapshot.json"
+}
+
+// writeSnapshot writes snapshot to path
+func writeSnapshot(path string, snapshot *Snapshot) error {
+	content, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	return createFile(path, string(content)+"\n")
+}
+
+// latestSnapshot reads the snapshot of the newest migration in the migration folder, an empty one when
+// there is none
+func (m *Migrator) latestSnapshot() (*Snapshot, error) {
+	entries, err := os.ReadDir(m.migrationPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var latest string
+	var latestVersion uint64
+	for _, entry := range entries {
+		match := regSnapshotFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		if version, err := strconv.ParseUint(match[1], 10, 64); err == nil && (latest == "" || version > latestVersion) {
+			latest, latestVersion = entry.Name(), version
+		}
+	}
+	snapshot := &Snapshot{Dialect: m.DB.Dialector.Name()}
+	if latest == "" {
+		return snapshot, nil
+	}
+
+	path := filepath.Join(m.migrationPath, latest)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, snapshot); err != nil {
+		return nil, fmt.Errorf("error reading snapshot %s: %w", path, err)
+	}
+	if snapshot.Dialect != m.DB.Dialector.Name() {
+		return nil, fmt.Errorf("snapshot %s was written for %s, not %s", path, snapshot.Dialect, m.DB.Dialector.Name())
+	}
+	return snapshot, nil
+}
+
+// snapshotDialect reads the catalog from a Snapshot instead of the database, everything else is left to
+// the dialect it wraps
+type snapshotDialect struct {
+	Dialect
+	snapshot *Snapshot
+}
+
+// snapshotRebuilder is the snapshotDialect of a dialect that rebuilds tables
+type snapshotRebuilder struct {
+	snapshotDialect
+	TableRebuilder
+}
+
+// table returns the snapshot table of stmt
+func (d snapshotDialect) table(stmt *gorm.Statement) (SnapshotTable, bool) {
+	for _, table := range d.snapshot.Tables {
+		if table.Name == stmt.Table {
+			return table, true
+		}
+	}
+	return SnapshotTable{}, false
+}
+
+func (d snapshotDialect) CurrentDatabase(m *Migrator) (string, error) {
+	return "", nil
+}
+
+func (d snapshotDialect) HasTable(m *Migrator, stmt *gorm.Statement) (bool, error) {
+	_, ok := d.table(stmt)
+	return ok, nil
+}
+
+func (d snapshotDialect) Tables(m *Migrator, stmt *gorm.Statement) ([]string, error) {
+	tables := make([]string, 0, len(d.snapshot.Tables))
+	for _, table := range d.snapshot.Tables {
+		tables = append(tables, table.Name)
+	}
+	return tables, nil
+}
+
+func (d snapshotDialect) HasIndex(m *Migrator, stmt *gorm.Statement, name string) (bool, error) {
+	table, _ := d.table(stmt)
+	_, ok := lookupIndex(table.Indexes, name)
+	return ok, nil
+}
+
+func (d snapshotDialect) ColumnTypes(m *Migrator, stmt *gorm.Statement) ([]ColumnType, error) {
+	table, _ := d.table(stmt)
+	columns := make([]ColumnType, 0, len(table.Columns))
+	for _, column := range table.Columns {
+		columns = append(columns, column.columnType())
+	}
+	return columns, nil
+}
+
+func (d snapshotDialect) Indexes(m *Migrator, stmt *gorm.Statement) ([]Index, error) {
+	table, _ := d.table(stmt)
+	return table.Indexes, nil
+}
+
+func (d snapshotDialect) ForeignKeys(m *Migrator, stmt *gorm.Statement) ([]ForeignKey, error) {
+	table, _ := d.table(stmt)
+	return table.ForeignKeys, nil
+}
+
+func (d snapshotDialect) Checks(m *Migrator, stmt *gorm.Statement) ([]Check, error) {
+	table, _ := d.table(stmt)
+	return table.Checks, nil
+}
+
+// withSnapshot calls fn with a migrator comparing the models with the latest snapshot in the migration
+// folder rather than a database
+func (mg *Migrator) withSnapshot(fn func(target *Migrator) error) error {
+	d, err := mg.dialect()
+	if err != nil {
+		return err
+	}
+	snapshot, err := mg.latestSnapshot()
+	if err != nil {
+		return err
+	}
+	offline := *mg
+	offline.Dialect = snapshotDialect{Dialect: d, snapshot: snapshot}
+	if rebuilder, ok := d.(TableRebuilder); ok {
+		offline.Dialect = snapshotRebuilder{snapshotDialect{Dialect: d, snapshot: snapshot}, rebuilder}
+	}
+	return fn(&offline)
+}
@@ -0,0 +1,74 @@
+package migrator
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	regMigrationFile = regexp.MustCompile(`^\d+_.*\.up\.sql$`)
+	regLineComment   = regexp.MustCompile(`--[^\n]*`)
+	regCreateTable   = regexp.MustCompile("(?i)CREATE\\s+TABLE\\s+(?:IF\\s+NOT\\s+EXISTS\\s+)?" + sqlTableName)
+	regRenameTable   = regexp.MustCompile("(?i)ALTER\\s+TABLE\\s+\\S+\\s+RENAME\\s+TO\\s+" + sqlTableName)
+	// SQL Server renames tables with sp_rename, columns are renamed with a third 'COLUMN' argument
+	regSPRenameTable = regexp.MustCompile(`(?i)sp_rename\s+'[^']*'\s*,\s*'([^']+)'\s*;`)
+)
+
+// sqlTableName matches a table name in a statement, quoted or not and optionally qualified by its schema
+const sqlTableName = "((?:[\"`\\[]?\\w+[\"`\\]]?\\.)?[\"`\\[]?\\w+[\"`\\]]?)"
+
+// migrationsTable is the table golang-migrate records applied versions in
+const migrationsTable = "schema_migrations"
+
+// ignoredTable reports whether table matches IgnoreTables or IgnoreTablePatterns
+func (m *Migrator) ignoredTable(table string) bool {
+	if table == migrationsTable {
+		return true
+	}
+	for _, pattern := range m.IgnoreTables {
+		if matched, err := path.Match(pattern, table); err == nil && matched {
+			return true
+		}
+	}
+	for _, pattern := range m.IgnoreTablePatterns {
+		if pattern.MatchString(table) {
+			return true
+		}
+	}
+	return false
+}
+
+// managedTables returns the tables created or renamed by the up migrations in the migration folder
+func (m *Migrator) managedTables() (map[string]bool, error) {
+	tables := map[string]bool{}
+	entries, err := os.ReadDir(m.migrationPath)
+	if os.IsNotExist(err) {
+		return tables, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !regMigrationFile.MatchString(entry.Name()) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(m.migrationPath, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		sql := regLineComment.ReplaceAllString(string(content), "")
+		for _, reg := range []*regexp.Regexp{regCreateTable, regRenameTable, regSPRenameTable} {
+			for _, match := range reg.FindAllStringSubmatch(sql, -1) {
+				name := match[1]
+				if i := strings.LastIndex(name, "."); i >= 0 {
+					name = name[i+1:]
+				}
+				tables[strings.Trim(name, "\"`[]")] = true
+			}
+		}
+	}
+	return tables, nil
+}